@@ -35,13 +35,7 @@ func (p Product) To() domain.Product {
 		p.ID,
 		p.Name,
 		p.Weight,
-		data.LazyLoadFn[domain.Company](func() (any, error) {
-			if company, err := data.LazyLoadNow[Company]("Company", &p); err != nil {
-				return nil, err
-			} else {
-				return company.To(), nil
-			}
-		}),
+		data.LazyLoadTo("Company", &p, Company.To),
 	)
 }
 func (p Product) From(m domain.Product) any {
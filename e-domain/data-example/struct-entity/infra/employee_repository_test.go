@@ -2,7 +2,7 @@ package infra_test
 
 import (
 	"context"
-	"database/sql"
+	"encoding/json"
 	"errors"
 	"github.com/reuben-baek/go-learning/e-domain/data"
 	"github.com/reuben-baek/go-learning/e-domain/data-example/struct-entity/domain"
@@ -160,6 +160,44 @@ func TestEmployeeRepository(t *testing.T) {
 			languages := found.Languages.Get()
 			assert.Equal(t, 1, len(languages))
 		})
+		t.Run("find-one with fetch plan", func(t *testing.T) {
+			var found domain.Employee
+			err := transactionManager.Do(ctx, func(ctx context.Context) error {
+				found, err = employeeRepository.FindOne(data.WithFetchPlan(ctx, "Manages.Category", "Departments.Company"), reuben.ID)
+				return err
+			})
+			assert.Nil(t, err)
+
+			// fetched by the plan, accessible after the transaction
+			manages, err := data.LoadLazy(found.Manages)
+			assert.Nil(t, err)
+			assert.Equal(t, 1, len(manages))
+			category, err := data.LoadLazy(manages[0].Category)
+			assert.Nil(t, err)
+			assert.Equal(t, cloud.ID, category.ID)
+			departments, err := data.LoadLazy(found.Departments)
+			assert.Nil(t, err)
+			assert.Equal(t, 1, len(departments))
+			company, err := data.LoadLazy(departments[0].Company)
+			assert.Nil(t, err)
+			assert.Equal(t, kakaoEnterprise, company)
+
+			// not in the plan, lazy
+			_, err = data.LoadLazy(found.Company)
+			assert.ErrorIs(t, err, data.TransactionClosedError)
+			_, err = data.LoadLazy(manages[0].Company)
+			assert.ErrorIs(t, err, data.TransactionClosedError)
+			// eager by tag, which is kept eager under the plan
+			assert.Equal(t, reuben.CreditCard, found.CreditCard)
+
+			marshalled, err := data.MarshalLazyJSON(found, data.LazyJSONReference, 0)
+			assert.Nil(t, err)
+			var employee map[string]any
+			json.Unmarshal(marshalled, &employee)
+			assert.Equal(t, map[string]any{"id": float64(kakaoEnterprise.ID)}, employee["Company"])
+			assert.Nil(t, employee["Languages"])
+			assert.Equal(t, "cloud", employee["Manages"].([]any)[0].(map[string]any)["Category"].(map[string]any)["Name"])
+		})
 		t.Run("find-by-company", func(t *testing.T) {
 			employees, err := employeeRepository.FindByCompany(ctx, kakaoEnterprise)
 			assert.Nil(t, err)
@@ -218,6 +256,41 @@ func TestEmployeeRepository(t *testing.T) {
 		})
 	})
 
+	t.Run("create aggregate of new entities", func(t *testing.T) {
+		kakaoBank := domain.Company{Name: "kakao bank"}
+		ryan := domain.Employee{
+			Name:    "ryan",
+			Company: data.LazyLoadValue[domain.Company](kakaoBank),
+			Manages: data.LazyLoadValue[[]domain.Product]([]domain.Product{{
+				Name:     "deposit",
+				Category: data.LazyLoadValue[domain.Category](domain.Category{Name: "banking"}),
+				Company:  data.LazyLoadValue[domain.Company](kakaoEnterprise),
+			}}),
+			CreditCard: domain.CreditCard{
+				Number: "222222222222",
+			},
+			Departments: data.LazyLoadValue[[]domain.Department]([]domain.Department{}),
+			Languages:   data.LazyLoadValue[[]domain.Language]([]domain.Language{korean}),
+		}
+		ryan, err := employeeRepository.Create(ctx, ryan)
+		assert.Nil(t, err)
+		assert.NotEmpty(t, ryan.ID)
+		assert.NotEmpty(t, ryan.CreditCard.ID)
+
+		found, err := employeeRepository.FindOne(ctx, ryan.ID)
+		assert.Nil(t, err)
+		company := found.Company.Get()
+		assert.NotEmpty(t, company.ID)
+		assert.Equal(t, kakaoBank.Name, company.Name)
+		manages := found.Manages.Get()
+		assert.Equal(t, 1, len(manages))
+		assert.NotEmpty(t, manages[0].ID)
+		assert.Equal(t, "banking", manages[0].Category.Get().Name)
+		assert.Equal(t, kakaoEnterprise, manages[0].Company.Get())
+		assert.Equal(t, "222222222222", found.CreditCard.Number)
+		assert.Equal(t, 1, len(found.Languages.Get()))
+	})
+
 	t.Run("update", func(t *testing.T) {
 		reuben := domain.Employee{
 			Name:    "reuben.b",
@@ -404,9 +477,11 @@ func TestEmployeeRepository(t *testing.T) {
 			assert.Nil(t, err)
 			assert.Equal(t, reuben.ID, found.ID)
 
-			assert.PanicsWithError(t, sql.ErrTxDone.Error(), func() {
-				foundCompany = found.Company.Get()
-				assert.Equal(t, kakaoEnterprise, foundCompany)
+			foundCompany, err = data.LoadLazy(found.Company)
+			assert.Equal(t, &data.LazyLoadScopeError{Entity: "infra.Company", Err: data.TransactionClosedError}, err)
+			assert.Equal(t, domain.Company{}, foundCompany)
+			assert.PanicsWithError(t, err.Error(), func() {
+				data.MustGetLazy(found.Company)
 			})
 		})
 	})
@@ -34,15 +34,11 @@ func (c Category) To() domain.Category {
 	return domain.Category{
 		ID:   c.ID,
 		Name: c.Name,
-		Parent: data.LazyLoadFn[domain.Category](func() (any, error) {
-			if category, err := data.LazyLoadNow[*Category]("Parent", &c); err != nil {
-				panic(err)
-			} else {
-				if category == nil {
-					return domain.Category{}, nil
-				}
-				return category.To(), nil
+		Parent: data.LazyLoadTo("Parent", &c, func(parent *Category) domain.Category {
+			if parent == nil {
+				return domain.Category{}
 			}
+			return parent.To()
 		}),
 	}
 }
@@ -72,29 +68,20 @@ type Product struct {
 
 func (p Product) To() domain.Product {
 	return domain.Product{
-		ID:   p.ID,
-		Name: p.Name,
-		Company: data.LazyLoadFn[domain.Company](func() (any, error) {
-			if company, err := data.LazyLoadNow[Company]("Company", &p); err != nil {
-				panic(err)
-			} else {
-				return company.To(), nil
-			}
-		}),
-		Category: data.LazyLoadFn[domain.Category](func() (any, error) {
-			if category, err := data.LazyLoadNow[Category]("Category", &p); err != nil {
-				panic(err)
-			} else {
-				return category.To(), nil
-			}
-		}),
+		ID:       p.ID,
+		Name:     p.Name,
+		Company:  data.LazyLoadTo("Company", &p, Company.To),
+		Category: data.LazyLoadTo("Category", &p, Category.To),
 	}
 }
 func (p Product) From(m domain.Product) any {
 	p.ID = m.ID
 	p.Name = m.Name
+	// new company and category are created with the product
 	p.CompanyID = m.Company.Get().ID
+	p.Company = p.Company.From(m.Company.Get()).(Company)
 	p.CategoryID = m.Category.Get().ID
+	p.Category = p.Category.From(m.Category.Get()).(Category)
 	return p
 }
 
@@ -112,33 +99,16 @@ type Department struct {
 
 func (d Department) To() domain.Department {
 	return domain.Department{
-		ID:   d.ID,
-		Name: d.Name,
-		Company: data.LazyLoadFn[domain.Company](func() (any, error) {
-			if company, err := data.LazyLoadNow[Company]("Company", &d); err != nil {
-				panic(err)
-			} else {
-				return company.To(), nil
-			}
-		}),
-		Upper: data.LazyLoadFn[domain.Department](func() (any, error) {
-			if upper, err := data.LazyLoadNow[*Department]("Upper", &d); err != nil {
-				panic(err)
-			} else {
-				if upper == nil {
-					return domain.Department{}, nil
-				} else {
-					return upper.To(), nil
-				}
-			}
-		}),
-		Manager: data.LazyLoadFn[domain.Employee](func() (any, error) {
-			if manager, err := data.LazyLoadNow[Employee]("Manager", &d); err != nil {
-				panic(err)
-			} else {
-				return manager.To(), nil
+		ID:      d.ID,
+		Name:    d.Name,
+		Company: data.LazyLoadTo("Company", &d, Company.To),
+		Upper: data.LazyLoadTo("Upper", &d, func(upper *Department) domain.Department {
+			if upper == nil {
+				return domain.Department{}
 			}
+			return upper.To()
 		}),
+		Manager: data.LazyLoadTo("Manager", &d, Employee.To),
 	}
 }
 func (d Department) From(m domain.Department) any {
@@ -177,49 +147,13 @@ type Employee struct {
 
 func (e Employee) To() domain.Employee {
 	return domain.Employee{
-		ID:   e.ID,
-		Name: e.Name,
-		Company: data.LazyLoadFn[domain.Company](func() (any, error) {
-			if company, err := data.LazyLoadNow[Company]("Company", &e); err != nil {
-				panic(err)
-			} else {
-				return company.To(), nil
-			}
-		}),
-		Manages: data.LazyLoadFn[[]domain.Product](func() (any, error) {
-			if products, err := data.LazyLoadNow[[]Product]("Manages", &e); err != nil {
-				panic(err)
-			} else {
-				ps := make([]domain.Product, 0, len(products))
-				for _, p := range products {
-					ps = append(ps, p.To())
-				}
-				return ps, nil
-			}
-		}),
-		CreditCard: e.CreditCard.To(),
-		Departments: data.LazyLoadFn[[]domain.Department](func() (any, error) {
-			if departments, err := data.LazyLoadNow[[]Department]("Departments", &e); err != nil {
-				panic(err)
-			} else {
-				ps := make([]domain.Department, 0, len(departments))
-				for _, p := range departments {
-					ps = append(ps, p.To())
-				}
-				return ps, nil
-			}
-		}),
-		Languages: data.LazyLoadFn[[]domain.Language](func() (any, error) {
-			if languages, err := data.LazyLoadNow[[]Language]("Languages", &e); err != nil {
-				panic(err)
-			} else {
-				ps := make([]domain.Language, 0, len(languages))
-				for _, p := range languages {
-					ps = append(ps, p.To())
-				}
-				return ps, nil
-			}
-		}),
+		ID:          e.ID,
+		Name:        e.Name,
+		Company:     data.LazyLoadTo("Company", &e, Company.To),
+		Manages:     data.LazyLoadTo("Manages", &e, toDomains[Product, domain.Product]),
+		CreditCard:  e.CreditCard.To(),
+		Departments: data.LazyLoadTo("Departments", &e, toDomains[Department, domain.Department]),
+		Languages:   data.LazyLoadTo("Languages", &e, toDomains[Language, domain.Language]),
 	}
 }
 
@@ -252,6 +186,7 @@ func (e Employee) From(m domain.Employee) any {
 		ID:          m.ID,
 		Name:        m.Name,
 		CompanyID:   m.Company.Get().ID,
+		Company:     Company{}.From(m.Company.Get()).(Company),
 		Manages:     manages,
 		CreditCard:  creditCard,
 		Departments: departments,
@@ -295,3 +230,11 @@ func (l Language) From(m domain.Language) any {
 	l.Name = m.Name
 	return l
 }
+
+func toDomains[E interface{ To() D }, D any](entities []E) []D {
+	domains := make([]D, 0, len(entities))
+	for _, entity := range entities {
+		domains = append(domains, entity.To())
+	}
+	return domains
+}
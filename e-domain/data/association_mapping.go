@@ -0,0 +1,136 @@
+package data
+
+import (
+	"fmt"
+	"gorm.io/gorm"
+	"gorm.io/gorm/schema"
+	"reflect"
+)
+
+// associationMapping is tables and columns of an association, resolved by GORM schema,
+// so that custom table names, column tags and the naming strategy of the database are honoured.
+//
+//	belong-to:    table.key = associationTable.associationKey, key is the foreign key
+//	has-one/many: table.key = associationTable.associationKey, associationKey is the foreign key
+//	many-to-many: table.key = joinTable.joinKey, joinTable.joinAssociationKey = associationTable.associationKey
+//
+// polymorphicType is the type column of polymorphic associations, in the table of the foreign key.
+type associationMapping struct {
+	table                 string
+	key                   string
+	keyField              string
+	associationTable      string
+	associationKey        string
+	associationKeyField   string
+	associationPrimaryKey string
+	joinTable             string
+	joinKey               string
+	joinAssociationKey    string
+	polymorphicType       string
+	polymorphicValue      string
+}
+
+// whereForeignKey returns conditions of the foreign key column, and the polymorphic type column if any.
+func (m associationMapping) whereForeignKey(foreignKey string, value any) map[string]any {
+	conditions := map[string]any{foreignKey: value}
+	if m.polymorphicType != "" {
+		conditions[m.polymorphicType] = m.polymorphicValue
+	}
+	return conditions
+}
+
+func parseSchema(db *gorm.DB, ptrToEntity any) (*schema.Schema, error) {
+	stmt := &gorm.Statement{DB: db}
+	if err := stmt.Parse(ptrToEntity); err != nil {
+		return nil, err
+	}
+	return stmt.Schema, nil
+}
+
+func lookUpColumn(s *schema.Schema, fieldName string) (string, error) {
+	field := s.LookUpField(fieldName)
+	if field == nil || field.DBName == "" {
+		return "", fmt.Errorf("%s has no column of field %s", s.Name, fieldName)
+	}
+	return field.DBName, nil
+}
+
+func resolveAssociation(db *gorm.DB, ptrToEntity any, association Association) (associationMapping, error) {
+	var mapping associationMapping
+	entitySchema, err := parseSchema(db, ptrToEntity)
+	if err != nil {
+		return mapping, err
+	}
+	associationType := reflect.TypeOf(association.PtrToEntity).Elem()
+	if associationType.Kind() == reflect.Slice {
+		associationType = associationType.Elem()
+	}
+	associationSchema, err := parseSchema(db, reflect.New(associationType).Interface())
+	if err != nil {
+		return mapping, err
+	}
+	mapping.table = entitySchema.Table
+	mapping.associationTable = associationSchema.Table
+	if associationSchema.PrioritizedPrimaryField != nil {
+		// join entities of composite keys have no prioritized one
+		mapping.associationPrimaryKey = associationSchema.PrioritizedPrimaryField.DBName
+	}
+
+	foreignKeyField := association.foreignKeyField(reflect.TypeOf(ptrToEntity).Elem())
+	switch association.Type {
+	case BelongTo:
+		mapping.keyField, mapping.associationKeyField = foreignKeyField, association.referencesField()
+	case HasOne, HasMany:
+		mapping.keyField, mapping.associationKeyField = association.referencesField(), foreignKeyField
+	case ManyToMany:
+		relationship, ok := entitySchema.Relationships.Relations[association.Name]
+		if !ok || relationship.JoinTable == nil {
+			return mapping, fmt.Errorf("%s of %s is not many-to-many association", association.Name, entitySchema.Name)
+		}
+		mapping.joinTable = relationship.JoinTable.Table
+		for _, reference := range relationship.References {
+			if reference.OwnPrimaryKey {
+				mapping.key, mapping.keyField = reference.PrimaryKey.DBName, reference.PrimaryKey.Name
+				mapping.joinKey = reference.ForeignKey.DBName
+			} else {
+				mapping.associationKey, mapping.associationKeyField = reference.PrimaryKey.DBName, reference.PrimaryKey.Name
+				mapping.joinAssociationKey = reference.ForeignKey.DBName
+			}
+		}
+		return mapping, nil
+	}
+	if association.Polymorphic != "" {
+		if err := resolvePolymorphic(&mapping, entitySchema, associationSchema, association); err != nil {
+			return mapping, err
+		}
+	}
+	if mapping.key, err = lookUpColumn(entitySchema, mapping.keyField); err != nil {
+		return mapping, err
+	}
+	if mapping.associationKey, err = lookUpColumn(associationSchema, mapping.associationKeyField); err != nil {
+		return mapping, err
+	}
+	return mapping, nil
+}
+
+func resolvePolymorphic(mapping *associationMapping, entitySchema *schema.Schema, associationSchema *schema.Schema, association Association) error {
+	var err error
+	if association.Type == BelongTo {
+		if mapping.polymorphicType, err = lookUpColumn(entitySchema, association.Polymorphic+"Type"); err != nil {
+			return err
+		}
+		value, ok := polymorphicValueOf(associationSchema.ModelType)
+		if !ok {
+			return fmt.Errorf("%s is not registered polymorphic type", associationSchema.Name)
+		}
+		mapping.polymorphicValue = value
+		return nil
+	}
+	relationship, ok := entitySchema.Relationships.Relations[association.Name]
+	if !ok || relationship.Polymorphic == nil {
+		return fmt.Errorf("%s of %s is not polymorphic association of GORM", association.Name, entitySchema.Name)
+	}
+	mapping.polymorphicType = relationship.Polymorphic.PolymorphicType.DBName
+	mapping.polymorphicValue = relationship.Polymorphic.Value
+	return nil
+}
@@ -0,0 +1,142 @@
+package data_test
+
+import (
+	"context"
+	"github.com/reuben-baek/go-learning/e-domain/data"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+// mapped entities are declared at package level to refer to each other.
+type MappedOwner struct {
+	data.LazyLoader `gorm:"-"`
+	ID              uint
+	Code            string `gorm:"uniqueIndex"`
+	Name            string
+	MappedPets      []MappedPet `assoc:"has_many;fk:OwnerCode;references:Code" gorm:"foreignKey:OwnerCode;references:Code"`
+}
+
+func (MappedOwner) TableName() string {
+	return "owner_accounts"
+}
+
+type MappedPet struct {
+	data.LazyLoader `gorm:"-"`
+	ID              uint
+	Name            string
+	OwnerCode       string
+	MappedOwner     *MappedOwner `assoc:"belongs_to;fk:OwnerCode;references:Code" gorm:"foreignKey:OwnerCode;references:Code"`
+}
+
+type MappedPerson struct {
+	data.LazyLoader `gorm:"-"`
+	ID              uint
+	Name            string
+	MappedPets      []MappedPet `assoc:"many_to_many" gorm:"many2many:pet_sitters"`
+}
+
+type MappedSitter struct {
+	data.LazyLoader `gorm:"-"`
+	ID              uint
+	Name            string
+	MappedPets      []MappedPet `assoc:"many_to_many" gorm:"many2many:sitter_pets;joinForeignKey:SitterID;joinReferences:PetID"`
+}
+
+func TestGormRepository_AssocTag(t *testing.T) {
+	db := getGormDB()
+	db.AutoMigrate(&MappedOwner{}, &MappedPet{}, &MappedPerson{})
+	assert.True(t, db.Migrator().HasTable("owner_accounts"))
+	assert.True(t, db.Migrator().HasTable("mapped_people"))
+
+	transactionManager := data.NewGormTransactionManager(db)
+	ownerRepository := data.NewGormRepository[MappedOwner, uint](transactionManager)
+	petRepository := data.NewGormRepository[MappedPet, uint](transactionManager)
+	personRepository := data.NewGormRepository[MappedPerson, uint](transactionManager)
+
+	reuben := MappedOwner{Code: "R-1", Name: "reuben"}
+	db.Create(&reuben)
+	db.Create(&MappedOwner{Code: "D-2", Name: "dennis"})
+	tom := MappedPet{Name: "tom", OwnerCode: "R-1"}
+	nabi := MappedPet{Name: "nabi", OwnerCode: "R-1"}
+	db.Create(&tom)
+	db.Create(&nabi)
+	db.Create(&MappedPet{Name: "happy", OwnerCode: "D-2"})
+	sitter := MappedPerson{Name: "sitter", MappedPets: []MappedPet{tom}}
+	db.Create(&sitter)
+
+	names := func(pets []MappedPet) []string {
+		var names []string
+		for _, pet := range pets {
+			names = append(names, pet.Name)
+		}
+		return names
+	}
+
+	ctx := context.Background()
+	t.Run("belongs-to by references", func(t *testing.T) {
+		err := transactionManager.Do(ctx, func(ctx context.Context) error {
+			pets, err := petRepository.FindBy(ctx, "MappedOwner", reuben)
+			assert.Nil(t, err)
+			assert.Equal(t, []string{"tom", "nabi"}, names(pets))
+
+			owner, err := data.LazyLoadNow[*MappedOwner]("MappedOwner", &pets[0])
+			assert.Nil(t, err)
+			assert.Equal(t, "reuben", owner.Name)
+			return nil
+		})
+		assert.Nil(t, err)
+	})
+	t.Run("has-many by references with custom table name", func(t *testing.T) {
+		err := transactionManager.Do(ctx, func(ctx context.Context) error {
+			owner, err := ownerRepository.FindOne(ctx, reuben.ID)
+			assert.Nil(t, err)
+			pets, err := data.LazyLoadNow[[]MappedPet]("MappedPets", &owner)
+			assert.Nil(t, err)
+			assert.Equal(t, []string{"tom", "nabi"}, names(pets))
+
+			owners, err := ownerRepository.FindBy(ctx, "MappedPet", nabi)
+			assert.Nil(t, err)
+			assert.Equal(t, 1, len(owners))
+			assert.Equal(t, "reuben", owners[0].Name)
+			return nil
+		})
+		assert.Nil(t, err)
+	})
+	t.Run("many-to-many with custom join table and irregular plural", func(t *testing.T) {
+		err := transactionManager.Do(ctx, func(ctx context.Context) error {
+			persons, err := personRepository.FindBy(ctx, "MappedPets", tom)
+			assert.Nil(t, err)
+			assert.Equal(t, 1, len(persons))
+			assert.Equal(t, "sitter", persons[0].Name)
+
+			pets, err := data.LazyLoadNow[[]MappedPet]("MappedPets", &persons[0])
+			assert.Nil(t, err)
+			assert.Equal(t, []string{"tom"}, names(pets))
+
+			persons, err = personRepository.FindBy(ctx, "MappedPets", nabi)
+			assert.Nil(t, err)
+			assert.Equal(t, 0, len(persons))
+			return nil
+		})
+		assert.Nil(t, err)
+	})
+	t.Run("many-to-many with custom join keys", func(t *testing.T) {
+		db.AutoMigrate(&MappedSitter{})
+		assert.True(t, db.Migrator().HasColumn("sitter_pets", "sitter_id"))
+		assert.True(t, db.Migrator().HasColumn("sitter_pets", "pet_id"))
+		db.Create(&MappedSitter{Name: "sitter", MappedPets: []MappedPet{nabi}})
+		sitterRepository := data.NewGormRepository[MappedSitter, uint](transactionManager)
+
+		err := transactionManager.Do(ctx, func(ctx context.Context) error {
+			sitters, err := sitterRepository.FindBy(ctx, "MappedPets", nabi)
+			assert.Nil(t, err)
+			assert.Equal(t, 1, len(sitters))
+
+			pets, err := data.LazyLoadNow[[]MappedPet]("MappedPets", &sitters[0])
+			assert.Nil(t, err)
+			assert.Equal(t, []string{"nabi"}, names(pets))
+			return nil
+		})
+		assert.Nil(t, err)
+	})
+}
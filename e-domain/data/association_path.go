@@ -0,0 +1,129 @@
+package data
+
+import (
+	"fmt"
+	"gorm.io/gorm"
+	"reflect"
+	"strings"
+)
+
+// isAssociationPath reports whether name of FindBy is a dotted path of associations, such as "Departments.Company".
+func isAssociationPath(name string) bool {
+	return strings.Contains(name, ".")
+}
+
+// associationPath is joins of a dotted path of associations from the table of an entity, and the condition of the
+// last association referring to byEntity.
+//
+//	select distinct employees.* from employees
+//	join employee_departments path_departments_join on employees.id = path_departments_join.employee_id
+//	join departments path_departments on path_departments_join.department_id = path_departments.id
+//	join companies path_departments_company on path_departments.company_id = path_departments_company.id
+//	where path_departments_company.id = 1
+type associationPath struct {
+	table  string
+	joins  []pathJoin
+	where  string
+	value  any
+	toMany bool // some hop is has-many or many-to-many, which duplicates rows of the entity
+}
+
+type pathJoin struct {
+	query  string
+	values []any
+}
+
+// pathAssociation returns the association of entityType named name, or name+"s", as FindBy does.
+func pathAssociation(entityType reflect.Type, name string) (associationMetadata, bool) {
+	for _, association := range metadataOf(entityType).associations {
+		if association.Name == name || association.Name == name+"s" {
+			return association, true
+		}
+	}
+	return associationMetadata{}, false
+}
+
+// resolveAssociationPath resolves path from the entity of ptrToEntity, joining tables of every hop by aliases of
+// the path, so the same table can be joined more than once, such as parents of categories.
+// Polymorphic belong-to is resolved by the type of byEntity, so it is allowed only at the end of path.
+func resolveAssociationPath(db *gorm.DB, ptrToEntity any, path string, byEntity any) (associationPath, error) {
+	var p associationPath
+	entitySchema, err := parseSchema(db, ptrToEntity)
+	if err != nil {
+		return p, err
+	}
+	p.table = entitySchema.Table
+
+	names := strings.Split(path, ".")
+	entityType := reflect.TypeOf(ptrToEntity).Elem()
+	alias := p.table
+	for i, name := range names {
+		last := i == len(names)-1
+		metadata, ok := pathAssociation(entityType, name)
+		if !ok {
+			return p, fmt.Errorf("%s has no association %s of path %s", entityType.Name(), name, path)
+		}
+		association := metadata.Association
+		if isPolymorphicBelongTo(association) {
+			if !last {
+				return p, fmt.Errorf("polymorphic %s.%s is allowed only at the end of path %s", entityType.Name(), association.Name, path)
+			}
+			association.PtrToEntity = reflect.New(reflect.Indirect(reflect.ValueOf(byEntity)).Type()).Interface()
+		} else {
+			association.PtrToEntity = reflect.New(metadata.entityType).Interface()
+		}
+		mapping, err := resolveAssociation(db, reflect.New(entityType).Interface(), association)
+		if err != nil {
+			return p, err
+		}
+
+		hop := "path_" + toSnakeCase(strings.Join(names[:i+1], ""))
+		switch association.Type {
+		case BelongTo, HasOne, HasMany:
+			join := pathJoin{query: fmt.Sprintf("join %s %s on %s.%s = %s.%s", mapping.associationTable, hop, alias, mapping.key, hop, mapping.associationKey)}
+			if mapping.polymorphicType != "" {
+				// the type column is of the entity for belong-to, and of the association for the others
+				typeTable := hop
+				if association.Type == BelongTo {
+					typeTable = alias
+				}
+				join.query += fmt.Sprintf(" and %s.%s = ?", typeTable, mapping.polymorphicType)
+				join.values = append(join.values, mapping.polymorphicValue)
+			}
+			p.joins = append(p.joins, join)
+		case ManyToMany:
+			joinTable := hop + "_join"
+			p.joins = append(p.joins,
+				pathJoin{query: fmt.Sprintf("join %s %s on %s.%s = %s.%s", mapping.joinTable, joinTable, alias, mapping.key, joinTable, mapping.joinKey)},
+				pathJoin{query: fmt.Sprintf("join %s %s on %s.%s = %s.%s", mapping.associationTable, hop, joinTable, mapping.joinAssociationKey, hop, mapping.associationKey)},
+			)
+		}
+		if association.Type == HasMany || association.Type == ManyToMany {
+			p.toMany = true
+		}
+
+		if last {
+			switch association.Type {
+			case HasOne, HasMany:
+				if mapping.associationPrimaryKey == "" {
+					return p, fmt.Errorf("%s has no primary key to find by", mapping.associationTable)
+				}
+				p.where = fmt.Sprintf("%s.%s = ?", hop, mapping.associationPrimaryKey)
+				p.value, _ = findID[any, any](byEntity)
+			default:
+				p.where = fmt.Sprintf("%s.%s = ?", hop, mapping.associationKey)
+				p.value = findIDValue(byEntity, mapping.associationKeyField)
+			}
+			return p, nil
+		}
+		entityType = metadata.entityType
+		if entityType.Kind() == reflect.Slice {
+			entityType = entityType.Elem()
+		}
+		if entityType.Kind() != reflect.Struct {
+			return p, fmt.Errorf("%s of path %s is not struct type", entityType, path)
+		}
+		alias = hop
+	}
+	return p, nil
+}
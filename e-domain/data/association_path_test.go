@@ -0,0 +1,184 @@
+package data_test
+
+import (
+	"context"
+	"github.com/reuben-baek/go-learning/e-domain/data"
+	"github.com/stretchr/testify/assert"
+	"sort"
+	"testing"
+)
+
+// path entities are declared at package level to refer to each other.
+type PathCompany struct {
+	data.LazyLoader `gorm:"-"`
+	ID              uint
+	Name            string
+	Departments     []PathDepartment `assoc:"has_many;fk:CompanyID" gorm:"foreignKey:CompanyID"`
+}
+
+type PathDepartment struct {
+	data.LazyLoader `gorm:"-"`
+	ID              uint
+	Name            string
+	CompanyID       uint
+	Company         PathCompany
+	Employees       []PathEmployee `gorm:"many2many:path_employee_departments"`
+}
+
+type PathEmployee struct {
+	data.LazyLoader `gorm:"-"`
+	ID              uint
+	Name            string
+	Departments     []PathDepartment `gorm:"many2many:path_employee_departments"`
+}
+
+type PathCategory struct {
+	data.LazyLoader `gorm:"-"`
+	ID              uint
+	Name            string
+	ParentID        *uint
+	Parent          *PathCategory
+}
+
+type PathProduct struct {
+	data.LazyLoader `gorm:"-"`
+	ID              uint
+	Name            string
+	CategoryID      uint
+	Category        PathCategory
+}
+
+func TestGormRepository_FindByPath(t *testing.T) {
+	db := getGormDB()
+	db.AutoMigrate(&PathCompany{}, &PathDepartment{}, &PathEmployee{}, &PathCategory{}, &PathProduct{})
+
+	transactionManager := data.NewGormTransactionManager(db)
+	companyRepository := data.NewGormRepository[PathCompany, uint](transactionManager)
+	employeeRepository := data.NewGormRepository[PathEmployee, uint](transactionManager)
+	productRepository := data.NewGormRepository[PathProduct, uint](transactionManager)
+
+	kakao := PathCompany{Name: "kakao"}
+	naver := PathCompany{Name: "naver"}
+	db.Create(&kakao)
+	db.Create(&naver)
+	platform := PathDepartment{Name: "platform", CompanyID: kakao.ID}
+	payment := PathDepartment{Name: "payment", CompanyID: kakao.ID}
+	search := PathDepartment{Name: "search", CompanyID: naver.ID}
+	db.Create(&platform)
+	db.Create(&payment)
+	db.Create(&search)
+	reuben := PathEmployee{Name: "reuben", Departments: []PathDepartment{platform, payment}}
+	ryan := PathEmployee{Name: "ryan", Departments: []PathDepartment{payment, search}}
+	tony := PathEmployee{Name: "tony", Departments: []PathDepartment{search}}
+	db.Omit("Departments.*").Create(&reuben)
+	db.Omit("Departments.*").Create(&ryan)
+	db.Omit("Departments.*").Create(&tony)
+
+	electronics := PathCategory{Name: "electronics"}
+	db.Create(&electronics)
+	computers := PathCategory{Name: "computers", ParentID: &electronics.ID}
+	phones := PathCategory{Name: "phones", ParentID: &electronics.ID}
+	books := PathCategory{Name: "books"}
+	db.Create(&computers)
+	db.Create(&phones)
+	db.Create(&books)
+	db.Create(&PathProduct{Name: "laptop", CategoryID: computers.ID})
+	db.Create(&PathProduct{Name: "smartphone", CategoryID: phones.ID})
+	db.Create(&PathProduct{Name: "novel", CategoryID: books.ID})
+
+	ctx := context.Background()
+	t.Run("many-to-many and belongs-to", func(t *testing.T) {
+		err := transactionManager.Do(ctx, func(ctx context.Context) error {
+			employees, err := employeeRepository.FindBy(ctx, "Departments.Company", kakao)
+			assert.Nil(t, err)
+			assert.Equal(t, []string{"reuben", "ryan"}, pathNames(employees, func(e PathEmployee) string { return e.Name }))
+
+			employees, err = employeeRepository.FindBy(ctx, "Department.Company", naver)
+			assert.Nil(t, err)
+			assert.Equal(t, []string{"ryan", "tony"}, pathNames(employees, func(e PathEmployee) string { return e.Name }))
+
+			departments, err := data.LazyLoadNow[[]PathDepartment]("Departments", &employees[1])
+			assert.Nil(t, err)
+			assert.NotEmpty(t, departments)
+			return nil
+		})
+		assert.Nil(t, err)
+	})
+	t.Run("self-referencing belongs-to", func(t *testing.T) {
+		err := transactionManager.Do(ctx, func(ctx context.Context) error {
+			products, err := productRepository.FindBy(ctx, "Category.Parent", electronics)
+			assert.Nil(t, err)
+			assert.Equal(t, []string{"laptop", "smartphone"}, pathNames(products, func(p PathProduct) string { return p.Name }))
+
+			products, err = productRepository.FindBy(ctx, "Category.Parent", books)
+			assert.Nil(t, err)
+			assert.Empty(t, products)
+			return nil
+		})
+		assert.Nil(t, err)
+	})
+	t.Run("has-many and many-to-many", func(t *testing.T) {
+		err := transactionManager.Do(ctx, func(ctx context.Context) error {
+			companies, err := companyRepository.FindBy(ctx, "Departments.Employees", ryan)
+			assert.Nil(t, err)
+			assert.Equal(t, []string{"kakao", "naver"}, pathNames(companies, func(c PathCompany) string { return c.Name }))
+
+			companies, err = companyRepository.FindBy(ctx, "Departments.Employees", reuben)
+			assert.Nil(t, err)
+			assert.Equal(t, []string{"kakao"}, pathNames(companies, func(c PathCompany) string { return c.Name }))
+			return nil
+		})
+		assert.Nil(t, err)
+	})
+	t.Run("unknown association of path", func(t *testing.T) {
+		err := transactionManager.Do(ctx, func(ctx context.Context) error {
+			_, err := employeeRepository.FindBy(ctx, "Departments.Manager", reuben)
+			return err
+		})
+		assert.ErrorContains(t, err, "PathDepartment has no association Manager of path Departments.Manager")
+	})
+}
+
+func TestInMemoryRepository_FindByPath(t *testing.T) {
+	dummyTransactionManager := data.NewDummyTransactionManager()
+	employeeRepository := data.NewInMemoryRepository[PathEmployee, uint](dummyTransactionManager)
+	productRepository := data.NewInMemoryRepository[PathProduct, uint](dummyTransactionManager)
+
+	kakao := PathCompany{ID: 1, Name: "kakao"}
+	naver := PathCompany{ID: 2, Name: "naver"}
+	platform := PathDepartment{ID: 1, Name: "platform", CompanyID: kakao.ID, Company: kakao}
+	search := PathDepartment{ID: 2, Name: "search", CompanyID: naver.ID, Company: naver}
+	electronics := PathCategory{ID: 1, Name: "electronics"}
+	computers := PathCategory{ID: 2, Name: "computers", ParentID: &electronics.ID, Parent: &electronics}
+
+	ctx := context.Background()
+	dummyTransactionManager.Do(ctx, func(ctx context.Context) error {
+		employeeRepository.Create(ctx, PathEmployee{ID: 1, Name: "reuben", Departments: []PathDepartment{platform}})
+		employeeRepository.Create(ctx, PathEmployee{ID: 2, Name: "ryan", Departments: []PathDepartment{platform, search}})
+		employeeRepository.Create(ctx, PathEmployee{ID: 3, Name: "tony", Departments: []PathDepartment{search}})
+		productRepository.Create(ctx, PathProduct{ID: 1, Name: "laptop", CategoryID: computers.ID, Category: computers})
+		productRepository.Create(ctx, PathProduct{ID: 2, Name: "novel", CategoryID: 3, Category: PathCategory{ID: 3, Name: "books"}})
+
+		employees, err := employeeRepository.FindBy(ctx, "Departments.Company", kakao)
+		assert.Nil(t, err)
+		assert.Equal(t, []string{"reuben", "ryan"}, pathNames(employees, func(e PathEmployee) string { return e.Name }))
+
+		products, err := productRepository.FindBy(ctx, "Category.Parent", electronics)
+		assert.Nil(t, err)
+		assert.Equal(t, []string{"laptop"}, pathNames(products, func(p PathProduct) string { return p.Name }))
+
+		_, err = employeeRepository.FindBy(ctx, "Departments.Manager", kakao)
+		assert.ErrorContains(t, err, "PathDepartment has no association Manager of path Departments.Manager")
+		return nil
+	})
+}
+
+// pathNames returns sorted names of entities, which FindBy returns in no particular order.
+func pathNames[T any](entities []T, name func(T) string) []string {
+	var names []string
+	for _, entity := range entities {
+		names = append(names, name(entity))
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,247 @@
+package data
+
+import (
+	"context"
+	"fmt"
+	"github.com/sirupsen/logrus"
+	"reflect"
+	"sync"
+)
+
+// batchLoader loads an association of sibling entities, fetched together, at the first access of any of them.
+// Results are grouped by key, which is the foreign key value for belong-to, and the referenced sibling key for the others.
+type batchLoader struct {
+	m       sync.Mutex
+	done    bool
+	keys    []any
+	load    func(keys []any) (map[string]reflect.Value, error)
+	missing func(key any) (any, error)
+	results map[string]reflect.Value
+}
+
+func batchKey(key any) string {
+	value := reflect.ValueOf(key)
+	if value.Kind() == reflect.Pointer {
+		value = value.Elem()
+	}
+	return fmt.Sprint(value.Interface())
+}
+
+func (b *batchLoader) loadFunc(ctx context.Context, ptrToEntity any, key any) func() (any, error) {
+	return func() (any, error) {
+		if err := checkLazyLoadScope(ctx, ptrToEntity); err != nil {
+			return nil, err
+		}
+		if key == nil {
+			return b.missing(nil)
+		}
+		b.m.Lock()
+		defer b.m.Unlock()
+		if !b.done {
+			keys := make([]any, 0, len(b.keys))
+			seen := make(map[string]bool)
+			for _, k := range b.keys {
+				if k != nil && !seen[batchKey(k)] {
+					seen[batchKey(k)] = true
+					keys = append(keys, reflect.Indirect(reflect.ValueOf(k)).Interface())
+				}
+			}
+			results, err := b.load(keys)
+			if err != nil {
+				return nil, err // not done, to be retried
+			}
+			b.results, b.done = results, true
+		}
+		if result, ok := b.results[batchKey(key)]; ok {
+			return cloneLoaded(result).Interface(), nil
+		}
+		return b.missing(key)
+	}
+}
+
+// cloneLoaded copies a loaded entity or slice of entities, so that siblings sharing it do not share load functions.
+func cloneLoaded(value reflect.Value) reflect.Value {
+	if value.Kind() == reflect.Slice {
+		cloned := reflect.MakeSlice(value.Type(), 0, value.Len())
+		for i := 0; i < value.Len(); i++ {
+			cloned = reflect.Append(cloned, cloneLoaded(value.Index(i)))
+		}
+		return cloned
+	}
+	cloned := reflect.New(value.Type())
+	cloned.Elem().Set(value)
+	if lazyLoader, ok := cloned.Interface().(interface{ cloneLoadFuncs() }); ok {
+		lazyLoader.cloneLoadFuncs()
+	}
+	return cloned.Elem()
+}
+
+// setBatchLoadFuncs replaces load functions of batch fetched associations of entities in elements,
+// with ones sharing a batchLoader per association.
+func (u *GormRepository[T, ID]) setBatchLoadFuncs(ctx context.Context, elements reflect.Value) {
+	var plan fetchPlan
+	if bound, ok := fetchPlanFrom(ctx, elements.Type().Elem()); ok {
+		plan = bound.plan
+	}
+	u.setBatchLoadFuncsByPlan(withoutFetchPlan(ctx), elements, plan)
+}
+
+func (u *GormRepository[T, ID]) setBatchLoadFuncsByPlan(ctx context.Context, elements reflect.Value, plan fetchPlan) {
+	if elements.Len() == 0 {
+		return
+	}
+	first := elements.Index(0).Addr().Interface()
+	if _, ok := first.(LazyLoadable); !ok {
+		return
+	}
+	for _, association := range findAssociations(first) {
+		if plan.fetchMode(association) != FetchBatchMode || association.Polymorphic != "" {
+			continue
+		}
+		mapping, err := resolveAssociation(u.getReadGormDB(ctx), first, association)
+		if err != nil {
+			logrus.Errorf("GormRepository.setBatchLoadFuncs: fail to resolve %s of %T - %v", association.Name, first, err)
+			continue
+		}
+		loader := u.newBatchLoader(ctx, first, association, mapping)
+		if loader == nil {
+			continue
+		}
+		for i := 0; i < elements.Len(); i++ {
+			ptrToElement := elements.Index(i).Addr().Interface()
+			key := findIDValue(ptrToElement, mapping.keyField)
+			loader.keys = append(loader.keys, key)
+			ptrToElement.(LazyLoadable).SetLoadFunc(association.Name, loader.loadFunc(ctx, association.PtrToEntity, key))
+		}
+		logrus.Debugf("GormRepository.setBatchLoadFuncs: %s of %d %T", association.Name, elements.Len(), first)
+	}
+}
+
+func (u *GormRepository[T, ID]) newBatchLoader(ctx context.Context, ptrToEntity any, association Association, mapping associationMapping) *batchLoader {
+	associationType := reflect.TypeOf(association.PtrToEntity).Elem()
+	notFound := func(key any) (any, error) {
+		return nil, NotFoundError
+	}
+	switch association.Type {
+	case BelongTo:
+		return &batchLoader{
+			load: func(keys []any) (map[string]reflect.Value, error) {
+				return u.batchFind(ctx, associationType, mapping.associationKey, keys, mapping.associationKeyField)
+			},
+			missing: func(key any) (any, error) {
+				if key == nil {
+					return nil, nil
+				}
+				return nil, NotFoundError
+			},
+		}
+	case HasOne:
+		return &batchLoader{
+			load: func(keys []any) (map[string]reflect.Value, error) {
+				return u.batchFind(ctx, associationType, mapping.associationKey, keys, mapping.associationKeyField)
+			},
+			missing: notFound,
+		}
+	case HasMany:
+		return &batchLoader{
+			load: func(keys []any) (map[string]reflect.Value, error) {
+				return u.batchFind(ctx, associationType, mapping.associationKey, keys, mapping.associationKeyField)
+			},
+			missing: func(key any) (any, error) {
+				return reflect.MakeSlice(associationType, 0, 0).Interface(), nil
+			},
+		}
+	case ManyToMany:
+		return &batchLoader{
+			load: func(keys []any) (map[string]reflect.Value, error) {
+				return u.batchFindWithJoinTable(ctx, mapping, associationType, keys)
+			},
+			missing: func(key any) (any, error) {
+				return reflect.MakeSlice(associationType, 0, 0).Interface(), nil
+			},
+		}
+	}
+	return nil
+}
+
+// batchFind finds entities of entityType, or element type of it if slice, whose column is in keys, grouped by groupField.
+// Grouped values are entities for struct entityType, and slices of entities for slice entityType.
+func (u *GormRepository[T, ID]) batchFind(ctx context.Context, entityType reflect.Type, column string, keys []any, groupField string) (map[string]reflect.Value, error) {
+	elementType := entityType
+	if entityType.Kind() == reflect.Slice {
+		elementType = entityType.Elem()
+	}
+	results := make(map[string]reflect.Value)
+	if len(keys) == 0 {
+		return results, nil
+	}
+	ptrToSlice := reflect.New(reflect.SliceOf(elementType))
+	db := u.getReadGormDB(ctx)
+	db = u.preload(ctx, db, reflect.New(elementType).Interface())
+	if err := db.Model(ptrToSlice.Interface()).Find(ptrToSlice.Interface(), fmt.Sprintf("%s IN ?", column), keys).Error; err != nil {
+		return nil, err
+	}
+
+	elements := ptrToSlice.Elem()
+	u.loadedElements(ctx, elements)
+	for i := 0; i < elements.Len(); i++ {
+		element := elements.Index(i)
+		key := batchKey(element.FieldByName(groupField).Interface())
+		if entityType.Kind() == reflect.Slice {
+			group, ok := results[key]
+			if !ok {
+				group = reflect.MakeSlice(entityType, 0, 1)
+			}
+			results[key] = reflect.Append(group, element)
+		} else if _, ok := results[key]; !ok {
+			results[key] = element
+		}
+	}
+	return results, nil
+}
+
+// batchFindWithJoinTable finds entities of many-to-many association of entities whose key is in keys, grouped by the key.
+func (u *GormRepository[T, ID]) batchFindWithJoinTable(ctx context.Context, mapping associationMapping, sliceType reflect.Type, keys []any) (map[string]reflect.Value, error) {
+	results := make(map[string]reflect.Value)
+	if len(keys) == 0 {
+		return results, nil
+	}
+
+	// select owner_id, association_id from join_table where owner_id in (...)
+	var joinRows []map[string]any
+	db := u.getReadGormDB(ctx)
+	if err := db.Table(mapping.joinTable).Select(mapping.joinKey, mapping.joinAssociationKey).
+		Where(fmt.Sprintf("%s IN ?", mapping.joinKey), keys).Find(&joinRows).Error; err != nil {
+		return nil, err
+	}
+	associationKeys := make([]any, 0, len(joinRows))
+	for _, row := range joinRows {
+		associationKeys = append(associationKeys, row[mapping.joinAssociationKey])
+	}
+	associations, err := u.batchFind(ctx, sliceType.Elem(), mapping.associationKey, associationKeys, mapping.associationKeyField)
+	if err != nil {
+		return nil, err
+	}
+	for _, row := range joinRows {
+		key := batchKey(row[mapping.joinKey])
+		association, ok := associations[batchKey(row[mapping.joinAssociationKey])]
+		if !ok {
+			continue
+		}
+		group, ok := results[key]
+		if !ok {
+			group = reflect.MakeSlice(sliceType, 0, 1)
+		}
+		results[key] = reflect.Append(group, association)
+	}
+	return results, nil
+}
+
+// loadedElements calls loaded for each entity in elements with its ID, and sets batch load functions of them.
+func (u *GormRepository[T, ID]) loadedElements(ctx context.Context, elements reflect.Value) {
+	for i := 0; i < elements.Len(); i++ {
+		ptrToElement := elements.Index(i).Addr().Interface()
+		u.loaded(ctx, ptrToElement, idValueOf(ptrToElement))
+	}
+	u.setBatchLoadFuncs(ctx, elements)
+}
@@ -0,0 +1,125 @@
+package data_test
+
+import (
+	"context"
+	"github.com/reuben-baek/go-learning/e-domain/data"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+	"regexp"
+	"testing"
+)
+
+// batch fetched entities are declared at package level to refer to each other.
+type BatchCompany struct {
+	ID   uint
+	Name string
+}
+type BatchTag struct {
+	ID   uint
+	Name string
+}
+type BatchProduct struct {
+	data.LazyLoader `gorm:"-"`
+	ID              uint
+	Name            string
+	BatchCompanyID  uint
+	BatchCompany    BatchCompany `fetch:"batch"`
+	BatchCategoryID *uint
+	BatchCategory   *BatchCategory `fetch:"batch"`
+	BatchTags       []BatchTag     `gorm:"many2many:batch_product_tags;" fetch:"batch"`
+}
+type BatchCategory struct {
+	data.LazyLoader `gorm:"-"`
+	ID              uint
+	Name            string
+	BatchProducts   []BatchProduct `fetch:"batch"`
+}
+
+func TestGormRepository_BatchFetch(t *testing.T) {
+	db := getGormDB()
+	db.AutoMigrate(&BatchCompany{}, &BatchTag{}, &BatchCategory{}, &BatchProduct{})
+
+	queries := map[string]int{}
+	from := regexp.MustCompile("FROM `(\\w+)`")
+	db.Callback().Query().After("gorm:query").Register("test:count_query", func(db *gorm.DB) {
+		if m := from.FindStringSubmatch(db.Statement.SQL.String()); m != nil {
+			queries[m[1]]++
+		}
+	})
+
+	transactionManager := data.NewGormTransactionManager(db)
+	productRepository := data.NewGormRepository[BatchProduct, uint](transactionManager)
+
+	kakao := BatchCompany{Name: "kakao"}
+	naver := BatchCompany{Name: "naver"}
+	db.Create(&kakao)
+	db.Create(&naver)
+	storage := BatchCategory{Name: "storage"}
+	compute := BatchCategory{Name: "compute"}
+	db.Create(&storage)
+	db.Create(&compute)
+	ssd := BatchTag{Name: "ssd"}
+	hdd := BatchTag{Name: "hdd"}
+	db.Create(&ssd)
+	db.Create(&hdd)
+	db.Create(&BatchProduct{Name: "object-storage", BatchCompanyID: kakao.ID, BatchCategoryID: &storage.ID, BatchTags: []BatchTag{ssd, hdd}})
+	db.Create(&BatchProduct{Name: "block-storage", BatchCompanyID: naver.ID, BatchCategoryID: &storage.ID, BatchTags: []BatchTag{ssd}})
+	db.Create(&BatchProduct{Name: "vm", BatchCompanyID: kakao.ID, BatchCategoryID: &compute.ID})
+	db.Create(&BatchProduct{Name: "bare-metal", BatchCompanyID: kakao.ID})
+
+	ctx := context.Background()
+	err := transactionManager.Do(ctx, func(ctx context.Context) error {
+		products, err := productRepository.FindBy(ctx, "BatchCompany", kakao)
+		if err != nil {
+			return err
+		}
+		assert.Equal(t, 3, len(products))
+
+		queries = map[string]int{}
+		var categories []BatchCategory
+		for i := range products {
+			company, err := data.LazyLoadNow[BatchCompany]("BatchCompany", &products[i])
+			assert.Nil(t, err)
+			assert.Equal(t, kakao, company)
+
+			category, err := data.LazyLoadNow[*BatchCategory]("BatchCategory", &products[i])
+			assert.Nil(t, err)
+			if category != nil {
+				categories = append(categories, *category)
+			}
+		}
+		assert.Equal(t, map[string]int{"batch_companies": 1, "batch_categories": 1}, queries)
+		assert.Equal(t, []string{"storage", "compute"}, []string{categories[0].Name, categories[1].Name})
+		assert.Nil(t, products[2].BatchCategory)
+
+		queries = map[string]int{}
+		var tags [][]string
+		for i := range products {
+			loaded, err := data.LazyLoadNow[[]BatchTag]("BatchTags", &products[i])
+			assert.Nil(t, err)
+			var names []string
+			for _, tag := range loaded {
+				names = append(names, tag.Name)
+			}
+			tags = append(tags, names)
+		}
+		assert.Equal(t, map[string]int{"batch_product_tags": 1, "batch_tags": 1}, queries)
+		assert.Equal(t, [][]string{{"ssd", "hdd"}, nil, nil}, tags)
+
+		queries = map[string]int{}
+		var names [][]string
+		for i := range categories {
+			loaded, err := data.LazyLoadNow[[]BatchProduct]("BatchProducts", &categories[i])
+			assert.Nil(t, err)
+			var productNames []string
+			for _, product := range loaded {
+				productNames = append(productNames, product.Name)
+			}
+			names = append(names, productNames)
+		}
+		assert.Equal(t, map[string]int{"batch_products": 1}, queries)
+		assert.Equal(t, [][]string{{"object-storage", "block-storage"}, {"vm"}}, names)
+		return nil
+	})
+	assert.Nil(t, err)
+}
@@ -0,0 +1,305 @@
+package data
+
+import (
+	"container/list"
+	"context"
+	"errors"
+	"fmt"
+	"github.com/sirupsen/logrus"
+	"reflect"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// CacheStore is a backend of CachingRepository.
+type CacheStore interface {
+	Get(key string) (any, bool)
+	Set(key string, value any, ttl time.Duration)
+	Delete(key string)
+	Len() int
+}
+
+// LRUCacheStore is an in-process CacheStore which evicts the least recently used entry beyond its size.
+type LRUCacheStore struct {
+	m     sync.Mutex
+	size  int
+	lru   *list.List
+	items map[string]*list.Element
+	now   func() time.Time
+}
+
+type lruCacheItem struct {
+	key       string
+	value     any
+	expiresAt time.Time
+}
+
+func NewLRUCacheStore(size int) *LRUCacheStore {
+	if size <= 0 {
+		panic(fmt.Sprintf("NewLRUCacheStore: wrong size - %d", size))
+	}
+	return &LRUCacheStore{
+		size:  size,
+		lru:   list.New(),
+		items: make(map[string]*list.Element),
+		now:   time.Now,
+	}
+}
+
+func (s *LRUCacheStore) Get(key string) (any, bool) {
+	s.m.Lock()
+	defer s.m.Unlock()
+	element, ok := s.items[key]
+	if !ok {
+		return nil, false
+	}
+	item := element.Value.(*lruCacheItem)
+	if !item.expiresAt.IsZero() && !s.now().Before(item.expiresAt) {
+		s.lru.Remove(element)
+		delete(s.items, key)
+		return nil, false
+	}
+	s.lru.MoveToFront(element)
+	return item.value, true
+}
+
+// Set stores value for ttl. Zero ttl means no expiration.
+func (s *LRUCacheStore) Set(key string, value any, ttl time.Duration) {
+	s.m.Lock()
+	defer s.m.Unlock()
+	var expiresAt time.Time
+	if ttl > 0 {
+		expiresAt = s.now().Add(ttl)
+	}
+	if element, ok := s.items[key]; ok {
+		element.Value = &lruCacheItem{key: key, value: value, expiresAt: expiresAt}
+		s.lru.MoveToFront(element)
+		return
+	}
+	s.items[key] = s.lru.PushFront(&lruCacheItem{key: key, value: value, expiresAt: expiresAt})
+	for s.lru.Len() > s.size {
+		oldest := s.lru.Back()
+		s.lru.Remove(oldest)
+		delete(s.items, oldest.Value.(*lruCacheItem).key)
+	}
+}
+
+func (s *LRUCacheStore) Delete(key string) {
+	s.m.Lock()
+	defer s.m.Unlock()
+	if element, ok := s.items[key]; ok {
+		s.lru.Remove(element)
+		delete(s.items, key)
+	}
+}
+
+func (s *LRUCacheStore) Len() int {
+	s.m.Lock()
+	defer s.m.Unlock()
+	return s.lru.Len()
+}
+
+type CacheStats struct {
+	Hits         uint64
+	Misses       uint64
+	NegativeHits uint64 // hits of cached NotFoundError, included in Hits
+	Size         int
+}
+
+type cacheConfig struct {
+	store       CacheStore
+	ttl         time.Duration
+	negativeTTL time.Duration
+}
+
+type CacheOption func(c *cacheConfig)
+
+func WithCacheStore(store CacheStore) CacheOption {
+	return func(c *cacheConfig) {
+		c.store = store
+	}
+}
+
+func WithCacheTTL(ttl time.Duration) CacheOption {
+	return func(c *cacheConfig) {
+		c.ttl = ttl
+	}
+}
+
+// WithNegativeCacheTTL caches NotFoundError of FindOne for ttl.
+func WithNegativeCacheTTL(ttl time.Duration) CacheOption {
+	return func(c *cacheConfig) {
+		c.negativeTTL = ttl
+	}
+}
+
+// CachingRepository is a second-level cache of FindOne in front of a Repository.
+// In a transaction, the cache is populated after commit, and invalidated on Update and Delete both at once and after commit.
+// Entities with lazy loads are not cached, as the loads are bound to the context of the find, and fail in other contexts.
+type CachingRepository[T any, ID comparable] struct {
+	repository   Repository[T, ID]
+	store        CacheStore
+	ttl          time.Duration
+	negativeTTL  time.Duration
+	keyPrefix    string
+	hits         atomic.Uint64
+	misses       atomic.Uint64
+	negativeHits atomic.Uint64
+}
+
+type notFoundCacheEntry struct{}
+
+func NewCachingRepository[T any, ID comparable](repository Repository[T, ID], options ...CacheOption) *CachingRepository[T, ID] {
+	config := cacheConfig{ttl: 10 * time.Minute}
+	for _, option := range options {
+		option(&config)
+	}
+	if config.store == nil {
+		config.store = NewLRUCacheStore(1000)
+	}
+	var entity T
+	return &CachingRepository[T, ID]{
+		repository:  repository,
+		store:       config.store,
+		ttl:         config.ttl,
+		negativeTTL: config.negativeTTL,
+		keyPrefix:   reflect.TypeOf(entity).String(),
+	}
+}
+
+func (c *CachingRepository[T, ID]) key(id ID) string {
+	return fmt.Sprintf("%s:%v", c.keyPrefix, id)
+}
+
+func (c *CachingRepository[T, ID]) FindOne(ctx context.Context, id ID) (T, error) {
+	key := c.key(id)
+	if cached, ok := c.store.Get(key); ok {
+		c.hits.Add(1)
+		if _, notFound := cached.(notFoundCacheEntry); notFound {
+			c.negativeHits.Add(1)
+			var zero T
+			return zero, NotFoundError
+		}
+		return cached.(T), nil
+	}
+	c.misses.Add(1)
+
+	found, err := c.repository.FindOne(ctx, id)
+	if err == nil {
+		if hasLazyLoads(reflect.ValueOf(&found).Elem(), make(map[uintptr]bool)) {
+			logrus.Debugf("CachingRepository.FindOne: %s is not cached, which has lazy loads", key)
+			return found, nil
+		}
+		c.populate(ctx, key, found, c.ttl)
+	} else if errors.Is(err, NotFoundError) && c.negativeTTL > 0 {
+		c.populate(ctx, key, notFoundCacheEntry{}, c.negativeTTL)
+	}
+	return found, err
+}
+
+func (c *CachingRepository[T, ID]) Create(ctx context.Context, entity T) (T, error) {
+	created, err := c.repository.Create(ctx, entity)
+	if err == nil {
+		if id, zero := findID[T, ID](created); !zero {
+			c.invalidate(ctx, c.key(id)) // negative cache entry
+		}
+	}
+	return created, err
+}
+
+func (c *CachingRepository[T, ID]) Update(ctx context.Context, entity T) (T, error) {
+	id, _ := findID[T, ID](entity)
+	c.invalidate(ctx, c.key(id))
+	return c.repository.Update(ctx, entity)
+}
+
+func (c *CachingRepository[T, ID]) Delete(ctx context.Context, entity T) error {
+	id, _ := findID[T, ID](entity)
+	c.invalidate(ctx, c.key(id))
+	return c.repository.Delete(ctx, entity)
+}
+
+func (c *CachingRepository[T, ID]) Stats() CacheStats {
+	return CacheStats{
+		Hits:         c.hits.Load(),
+		Misses:       c.misses.Load(),
+		NegativeHits: c.negativeHits.Load(),
+		Size:         c.store.Len(),
+	}
+}
+
+// populate caches value, after commit if ctx is in a transaction.
+func (c *CachingRepository[T, ID]) populate(ctx context.Context, key string, value any, ttl time.Duration) {
+	if err := AfterCommit(ctx, func(ctx context.Context) {
+		c.store.Set(key, value, ttl)
+	}); err != nil {
+		c.store.Set(key, value, ttl)
+	} else {
+		logrus.Debugf("CachingRepository.populate: %s is cached after commit", key)
+	}
+}
+
+// invalidate removes key, and once more after commit if ctx is in a transaction.
+func (c *CachingRepository[T, ID]) invalidate(ctx context.Context, key string) {
+	c.store.Delete(key)
+	AfterCompletion(ctx, func(ctx context.Context, status TransactionStatus) {
+		c.store.Delete(key)
+	})
+}
+
+// hasLazyLoads reports whether value has LazyLoadable entities or LazyLoad not loaded yet.
+func hasLazyLoads(value reflect.Value, visited map[uintptr]bool) bool {
+	switch value.Kind() {
+	case reflect.Pointer, reflect.Interface:
+		if value.IsNil() {
+			return false
+		}
+		if value.Kind() == reflect.Pointer {
+			if hasLazyLoad(value.Interface()) {
+				return true
+			}
+			if visited[value.Pointer()] {
+				return false
+			}
+			visited[value.Pointer()] = true
+		}
+		return hasLazyLoads(value.Elem(), visited)
+	case reflect.Struct:
+		if value.CanAddr() && hasLazyLoad(value.Addr().Interface()) {
+			return true
+		}
+		if reflect.PointerTo(value.Type()).Implements(reflect.TypeOf((*LazyLoadable)(nil)).Elem()) {
+			return true
+		}
+		for i := 0; i < value.NumField(); i++ {
+			if value.Type().Field(i).IsExported() && hasLazyLoads(value.Field(i), visited) {
+				return true
+			}
+		}
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < value.Len(); i++ {
+			if hasLazyLoads(value.Index(i), visited) {
+				return true
+			}
+		}
+	case reflect.Map:
+		iter := value.MapRange()
+		for iter.Next() {
+			if hasLazyLoads(iter.Value(), visited) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func hasLazyLoad(ptr any) bool {
+	switch v := ptr.(type) {
+	case LazyLoadable:
+		return true
+	case interface{ pending() bool }:
+		return v.pending()
+	}
+	return false
+}
@@ -0,0 +1,171 @@
+package data_test
+
+import (
+	"context"
+	"errors"
+	"github.com/reuben-baek/go-learning/e-domain/data"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+type countingRepository[T any, ID comparable] struct {
+	data.Repository[T, ID]
+	findOneCount int
+}
+
+func (c *countingRepository[T, ID]) FindOne(ctx context.Context, id ID) (T, error) {
+	c.findOneCount++
+	return c.Repository.FindOne(ctx, id)
+}
+
+func TestCachingRepository(t *testing.T) {
+	type Language struct {
+		ID   string
+		Name string
+	}
+
+	transactionManager := data.NewDummyTransactionManager()
+	ctx := context.Background()
+	// InMemoryRepository works in a transaction only, so each call runs in its own transaction.
+	inTransaction := func(f func(ctx context.Context) error) error {
+		return transactionManager.Do(ctx, f)
+	}
+	findOne := func(repository data.Repository[Language, string], id string) (found Language, err error) {
+		inTransaction(func(ctx context.Context) error {
+			found, err = repository.FindOne(ctx, id)
+			return nil
+		})
+		return found, err
+	}
+	newRepository := func(options ...data.CacheOption) (*data.CachingRepository[Language, string], *countingRepository[Language, string]) {
+		repository := &countingRepository[Language, string]{Repository: data.NewInMemoryRepository[Language, string](transactionManager)}
+		inTransaction(func(ctx context.Context) error {
+			repository.Create(ctx, Language{ID: "kr", Name: "korean"})
+			repository.Create(ctx, Language{ID: "en", Name: "english"})
+			repository.Create(ctx, Language{ID: "jp", Name: "japanese"})
+			return nil
+		})
+		return data.NewCachingRepository[Language, string](repository, options...), repository
+	}
+
+	t.Run("hit and miss", func(t *testing.T) {
+		cachingRepository, repository := newRepository()
+		for i := 0; i < 3; i++ {
+			found, err := findOne(cachingRepository, "kr")
+			assert.Nil(t, err)
+			assert.Equal(t, "korean", found.Name)
+		}
+		assert.Equal(t, 1, repository.findOneCount)
+		assert.Equal(t, data.CacheStats{Hits: 2, Misses: 1, Size: 1}, cachingRepository.Stats())
+	})
+	t.Run("ttl", func(t *testing.T) {
+		cachingRepository, repository := newRepository(data.WithCacheTTL(10 * time.Millisecond))
+		findOne(cachingRepository, "kr")
+		time.Sleep(15 * time.Millisecond)
+		findOne(cachingRepository, "kr")
+		assert.Equal(t, 2, repository.findOneCount)
+	})
+	t.Run("lru", func(t *testing.T) {
+		cachingRepository, repository := newRepository(data.WithCacheStore(data.NewLRUCacheStore(2)))
+		findOne(cachingRepository, "kr")
+		findOne(cachingRepository, "en")
+		findOne(cachingRepository, "kr")
+		findOne(cachingRepository, "jp") // evicts en
+		assert.Equal(t, 2, cachingRepository.Stats().Size)
+
+		repository.findOneCount = 0
+		findOne(cachingRepository, "kr")
+		findOne(cachingRepository, "en")
+		assert.Equal(t, 1, repository.findOneCount)
+	})
+	t.Run("negative caching", func(t *testing.T) {
+		cachingRepository, repository := newRepository(data.WithNegativeCacheTTL(time.Minute))
+		_, err := findOne(cachingRepository, "fr")
+		assert.Equal(t, data.NotFoundError, err)
+		_, err = findOne(cachingRepository, "fr")
+		assert.Equal(t, data.NotFoundError, err)
+		assert.Equal(t, 1, repository.findOneCount)
+		assert.Equal(t, uint64(1), cachingRepository.Stats().NegativeHits)
+
+		inTransaction(func(ctx context.Context) error {
+			_, err := cachingRepository.Create(ctx, Language{ID: "fr", Name: "french"})
+			return err
+		})
+		found, err := findOne(cachingRepository, "fr")
+		assert.Nil(t, err)
+		assert.Equal(t, "french", found.Name)
+	})
+	t.Run("invalidate on update and delete", func(t *testing.T) {
+		cachingRepository, _ := newRepository()
+		findOne(cachingRepository, "kr")
+		inTransaction(func(ctx context.Context) error {
+			_, err := cachingRepository.Update(ctx, Language{ID: "kr", Name: "hangul"})
+			return err
+		})
+		found, _ := findOne(cachingRepository, "kr")
+		assert.Equal(t, "hangul", found.Name)
+
+		inTransaction(func(ctx context.Context) error {
+			return cachingRepository.Delete(ctx, found)
+		})
+		_, err := findOne(cachingRepository, "kr")
+		assert.Equal(t, data.NotFoundError, err)
+	})
+	t.Run("populate after commit", func(t *testing.T) {
+		cachingRepository, _ := newRepository()
+		inTransaction(func(ctx context.Context) error {
+			cachingRepository.FindOne(ctx, "kr")
+			assert.Equal(t, 0, cachingRepository.Stats().Size)
+			return nil
+		})
+		assert.Equal(t, 1, cachingRepository.Stats().Size)
+
+		inTransaction(func(ctx context.Context) error {
+			cachingRepository.FindOne(ctx, "en")
+			return errors.New("rollback")
+		})
+		assert.Equal(t, 1, cachingRepository.Stats().Size)
+	})
+}
+
+func TestCachingRepository_LazyLoad(t *testing.T) {
+	type Company struct {
+		ID   uint
+		Name string
+	}
+	type Product struct {
+		data.LazyLoader `gorm:"-"`
+		ID              uint
+		Name            string
+		CompanyID       uint
+		Company         Company
+	}
+
+	db := getGormDB()
+	db.AutoMigrate(&Company{}, &Product{})
+	kakao := Company{Name: "kakao"}
+	db.Create(&kakao)
+	product := Product{Name: "object-storage", CompanyID: kakao.ID}
+	db.Create(&product)
+
+	transactionManager := data.NewGormTransactionManager(db)
+	repository := &countingRepository[Product, uint]{Repository: data.NewGormRepository[Product, uint](transactionManager)}
+	cachingRepository := data.NewCachingRepository[Product, uint](repository)
+
+	for i := 0; i < 2; i++ {
+		err := transactionManager.Do(context.Background(), func(ctx context.Context) error {
+			found, err := cachingRepository.FindOne(ctx, product.ID)
+			if err != nil {
+				return err
+			}
+			company, err := data.LazyLoadNow[Company]("Company", &found)
+			assert.Nil(t, err)
+			assert.Equal(t, kakao, company)
+			return nil
+		})
+		assert.Nil(t, err)
+	}
+	assert.Equal(t, 2, repository.findOneCount)
+	assert.Equal(t, 0, cachingRepository.Stats().Size)
+}
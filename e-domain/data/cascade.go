@@ -0,0 +1,115 @@
+package data
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// CascadeType is a set of operations on an entity cascaded to its association, declared by cascade tag such as
+// `cascade:"persist,merge"`. Without the tag, has-one, has-many and many-to-many associations cascade all,
+// and belong-to associations cascade persist.
+type CascadeType int
+
+const (
+	// CascadePersist creates the association with Create.
+	CascadePersist CascadeType = 1 << iota
+	// CascadeMerge replaces the association with Update. Entities removed from it are unlinked.
+	CascadeMerge
+	// CascadeRemove deletes has-one and has-many associated entities with Delete, which are unlinked otherwise.
+	// Join rows of many-to-many associations are always deleted.
+	CascadeRemove
+	// CascadeOrphanRemoval deletes entities removed from has-one and has-many associations, instead of unlinking them.
+	CascadeOrphanRemoval
+
+	CascadeNone CascadeType = 0
+	CascadeAll              = CascadePersist | CascadeMerge | CascadeRemove | CascadeOrphanRemoval
+)
+
+var cascadeTypes = map[string]CascadeType{
+	"persist":        CascadePersist,
+	"merge":          CascadeMerge,
+	"remove":         CascadeRemove,
+	"orphan_removal": CascadeOrphanRemoval,
+	"all":            CascadeAll,
+}
+
+func (c CascadeType) has(cascade CascadeType) bool {
+	return c&cascade == cascade
+}
+
+// parseCascadeTag returns cascade of the association field, panicking if it is wrong for the association type.
+func parseCascadeTag(entityType reflect.Type, field reflect.StructField, associationType AssociationType) CascadeType {
+	tag, ok := field.Tag.Lookup("cascade")
+	if !ok {
+		switch associationType {
+		case BelongTo:
+			return CascadePersist
+		case ManyToMany:
+			return CascadeAll &^ CascadeOrphanRemoval
+		}
+		return CascadeAll
+	}
+
+	cascade := CascadeNone
+	all := false
+	options := strings.Split(tag, ",")
+	for _, option := range options {
+		option = strings.TrimSpace(option)
+		if option == "none" {
+			if len(options) > 1 {
+				panic(fmt.Sprintf("cascade: none of %s.%s is combined with other options", entityType.Name(), field.Name))
+			}
+			continue
+		}
+		c, ok := cascadeTypes[option]
+		all = all || option == "all"
+		if !ok {
+			panic(fmt.Sprintf("cascade: wrong option '%s' of %s.%s", option, entityType.Name(), field.Name))
+		}
+		cascade |= c
+	}
+
+	switch associationType {
+	case BelongTo:
+		if cascade&^CascadePersist != 0 {
+			panic(fmt.Sprintf("cascade: belong-to %s.%s cascades persist only", entityType.Name(), field.Name))
+		}
+	case ManyToMany:
+		if all {
+			cascade &^= CascadeOrphanRemoval
+		}
+		if cascade.has(CascadeOrphanRemoval) {
+			panic(fmt.Sprintf("cascade: many-to-many %s.%s can not remove orphans, which may be shared", entityType.Name(), field.Name))
+		}
+	}
+	return cascade
+}
+
+// ValidateMappings parses association and cascade tags of entities, and returns errors of them.
+// NewGormRepository validates its entity type, and panics with the error.
+func ValidateMappings(entities ...any) error {
+	for _, entity := range entities {
+		entityType := reflect.TypeOf(entity)
+		for entityType.Kind() == reflect.Pointer || entityType.Kind() == reflect.Slice {
+			entityType = entityType.Elem()
+		}
+		if err := validateMapping(entityType); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func validateMapping(entityType reflect.Type) (err error) {
+	if entityType.Kind() != reflect.Struct {
+		return nil
+	}
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("%s: %v", entityType, r)
+		}
+	}()
+	metadataOf(entityType)
+	return nil
+}
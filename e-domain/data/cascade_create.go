@@ -0,0 +1,253 @@
+package data
+
+import (
+	"fmt"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"reflect"
+)
+
+// graphStore stores entities of an aggregate graph created by createGraph.
+type graphStore interface {
+	// insert creates the entity of ptrToEntity without its associations, and sets its generated ID.
+	insert(ptrToEntity any) error
+	// polymorphicValue returns the value of the type column of entities of a polymorphic has-one or has-many
+	// association of ptrToEntity.
+	polymorphicValue(ptrToEntity any, association associationMetadata) (string, error)
+	// link links existing entities to the created entity of ptrToEntity, by their foreign keys for has-one and has-many,
+	// and by join rows for many-to-many, which are written for new entities too.
+	link(ptrToEntity any, association associationMetadata, ptrToElements []any) error
+}
+
+// createGraph creates the entity of ptrToEntity and new entities reachable by associations of cascade persist, in the
+// order of foreign keys: belong-to parents first, then the entity, then has-one and has-many children and elements of
+// many-to-many with their join rows. Entities of zero ID are new, and so are entities without ID field, such as join
+// entities. Generated IDs and foreign keys are set in the graph, and created entities are returned in the order.
+func createGraph(store graphStore, ptrToEntity any) ([]any, error) {
+	creator := &graphCreator{store: store, visited: make(map[any]bool)}
+	if err := creator.create(ptrToEntity); err != nil {
+		return nil, err
+	}
+	return creator.created, nil
+}
+
+type graphCreator struct {
+	store   graphStore
+	visited map[any]bool // entities of pointers may refer to each other
+	created []any
+}
+
+func (c *graphCreator) create(ptrToEntity any) error {
+	if c.visited[ptrToEntity] {
+		return nil
+	}
+	c.visited[ptrToEntity] = true
+	entityValue := reflect.ValueOf(ptrToEntity).Elem()
+	metadata := metadataOf(entityValue.Type())
+
+	for _, association := range metadata.associations {
+		if association.Type != BelongTo || !association.cascade.has(CascadePersist) {
+			continue
+		}
+		field, _ := metadata.field(entityValue, association.Name)
+		ptrToParent, ok := graphNodeOf(field)
+		if !ok {
+			continue
+		}
+		if isNewEntity(ptrToParent) {
+			if err := c.create(ptrToParent); err != nil {
+				return err
+			}
+			setGraphNode(field, ptrToParent)
+		}
+		if err := setBelongTo(entityValue, association, ptrToParent); err != nil {
+			return err
+		}
+	}
+
+	if err := c.store.insert(ptrToEntity); err != nil {
+		return err
+	}
+	c.created = append(c.created, ptrToEntity)
+
+	for _, association := range metadata.associations {
+		if (association.Type != HasOne && association.Type != HasMany && association.Type != ManyToMany) || !association.cascade.has(CascadePersist) {
+			continue
+		}
+		field, _ := metadata.field(entityValue, association.Name)
+		var polymorphicValue string
+		if association.Polymorphic != "" {
+			var err error
+			if polymorphicValue, err = c.store.polymorphicValue(ptrToEntity, association); err != nil {
+				return err
+			}
+		}
+		var linked []any
+		for _, element := range graphElementsOf(field) {
+			ptrToElement, ok := graphNodeOf(element)
+			if !ok {
+				continue
+			}
+			if association.Type != ManyToMany {
+				setForeignKey(reflect.ValueOf(ptrToElement).Elem(), association.foreignKeyField(entityValue.Type()), findIDValue(ptrToEntity, association.referencesField()))
+				if association.Polymorphic != "" {
+					setForeignKey(reflect.ValueOf(ptrToElement).Elem(), association.Polymorphic+"Type", polymorphicValue)
+				}
+			}
+			if isNewEntity(ptrToElement) {
+				if err := c.create(ptrToElement); err != nil {
+					return err
+				}
+				setGraphNode(element, ptrToElement)
+				if association.Type != ManyToMany {
+					continue
+				}
+			}
+			linked = append(linked, ptrToElement)
+		}
+		if len(linked) > 0 {
+			if err := c.store.link(ptrToEntity, association, linked); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// setBelongTo sets the foreign key of entityValue to ptrToParent, and the type of polymorphic one.
+func setBelongTo(entityValue reflect.Value, association associationMetadata, ptrToParent any) error {
+	if association.Polymorphic != "" {
+		parentType := reflect.TypeOf(ptrToParent).Elem()
+		value, ok := polymorphicValueOf(parentType)
+		if !ok {
+			return fmt.Errorf("%s is not registered polymorphic type", parentType.Name())
+		}
+		setForeignKey(entityValue, association.Polymorphic+"Type", value)
+	}
+	setForeignKey(entityValue, association.belongToField, findIDValue(ptrToParent, association.referencesField()))
+	return nil
+}
+
+// setForeignKey sets the field of entityValue named name to value, converting it to the field type, such as *uint.
+func setForeignKey(entityValue reflect.Value, name string, value any) {
+	field, ok := metadataOf(entityValue.Type()).field(entityValue, name)
+	if !ok || value == nil {
+		return
+	}
+	v := reflect.Indirect(reflect.ValueOf(value))
+	if field.Kind() == reflect.Pointer {
+		field.Set(reflect.New(field.Type().Elem()))
+		field = field.Elem()
+	}
+	field.Set(v.Convert(field.Type()))
+}
+
+// graphElementsOf returns elements of an association field, which is a slice for to-many associations.
+func graphElementsOf(field reflect.Value) []reflect.Value {
+	if field.Kind() != reflect.Slice {
+		return []reflect.Value{field}
+	}
+	elements := make([]reflect.Value, field.Len())
+	for i := range elements {
+		elements[i] = field.Index(i)
+	}
+	return elements
+}
+
+// graphNodeOf returns a pointer to the entity of an association field or element, or false if it is nil or empty.
+// An entity in an interface is copied, to be set back by setGraphNode.
+func graphNodeOf(value reflect.Value) (any, bool) {
+	if value.Kind() == reflect.Interface {
+		if value.IsNil() {
+			return nil, false
+		}
+		value = value.Elem()
+		if value.Kind() == reflect.Struct {
+			ptrToEntity := reflect.New(value.Type())
+			ptrToEntity.Elem().Set(value)
+			return ptrToEntity.Interface(), true
+		}
+	}
+	if value.Kind() == reflect.Pointer {
+		if value.IsNil() || value.Elem().Kind() != reflect.Struct {
+			return nil, false
+		}
+		return value.Interface(), true
+	}
+	if value.Kind() != reflect.Struct || isEmptyEntity(value) {
+		return nil, false
+	}
+	return value.Addr().Interface(), true
+}
+
+// setGraphNode sets the entity of ptrToEntity created from an interface field back to it.
+func setGraphNode(value reflect.Value, ptrToEntity any) {
+	if value.Kind() != reflect.Interface || value.Elem().Kind() != reflect.Struct {
+		return
+	}
+	entityValue := reflect.ValueOf(ptrToEntity).Elem()
+	if entityValue.Type().AssignableTo(value.Type()) {
+		value.Set(entityValue)
+	} else {
+		// implementing the interface by pointer receivers
+		value.Set(reflect.ValueOf(ptrToEntity))
+	}
+}
+
+// isEmptyEntity reports whether every field of entityValue but lazy loaders is zero, such as an association not set.
+func isEmptyEntity(entityValue reflect.Value) bool {
+	for i := 0; i < entityValue.NumField(); i++ {
+		if entityValue.Type().Field(i).Type != reflect.TypeOf(LazyLoader{}) && !entityValue.Field(i).IsZero() {
+			return false
+		}
+	}
+	return true
+}
+
+func isNewEntity(ptrToEntity any) bool {
+	return idValueOf(ptrToEntity) == nil
+}
+
+// gormGraphStore stores an aggregate graph by GORM, inserting each entity without associations of GORM.
+type gormGraphStore struct {
+	db *gorm.DB
+}
+
+func (s gormGraphStore) insert(ptrToEntity any) error {
+	return s.db.Omit(clause.Associations).Create(ptrToEntity).Error
+}
+
+func (s gormGraphStore) polymorphicValue(ptrToEntity any, association associationMetadata) (string, error) {
+	mapping, err := s.resolve(ptrToEntity, association)
+	return mapping.polymorphicValue, err
+}
+
+func (s gormGraphStore) link(ptrToEntity any, association associationMetadata, ptrToElements []any) error {
+	mapping, err := s.resolve(ptrToEntity, association)
+	if err != nil {
+		return err
+	}
+	key := findIDValue(ptrToEntity, mapping.keyField)
+	switch association.Type {
+	case HasOne, HasMany:
+		// existing children are moved to the entity
+		for _, ptrToElement := range ptrToElements {
+			if err := s.db.Model(ptrToElement).Updates(mapping.whereForeignKey(mapping.associationKey, key)).Error; err != nil {
+				return err
+			}
+		}
+	case ManyToMany:
+		rows := make([]map[string]any, len(ptrToElements))
+		for i, ptrToElement := range ptrToElements {
+			rows[i] = map[string]any{mapping.joinKey: key, mapping.joinAssociationKey: findIDValue(ptrToElement, mapping.associationKeyField)}
+		}
+		return s.db.Table(mapping.joinTable).Clauses(clause.OnConflict{DoNothing: true}).Create(&rows).Error
+	}
+	return nil
+}
+
+func (s gormGraphStore) resolve(ptrToEntity any, association associationMetadata) (associationMapping, error) {
+	a := association.Association
+	a.PtrToEntity = reflect.New(association.entityType).Interface()
+	return resolveAssociation(s.db, ptrToEntity, a)
+}
@@ -0,0 +1,187 @@
+package data_test
+
+import (
+	"context"
+	"github.com/reuben-baek/go-learning/e-domain/data"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+	"testing"
+)
+
+type GraphCompany struct {
+	ID   uint
+	Name string
+}
+
+type GraphCategory struct {
+	ID   uint
+	Name string
+}
+
+type GraphCard struct {
+	ID              uint
+	Number          string
+	GraphEmployeeID uint
+}
+
+type GraphProduct struct {
+	data.LazyLoader `gorm:"-"`
+	ID              uint
+	Name            string
+	GraphEmployeeID uint
+	CompanyID       uint
+	Company         GraphCompany
+	CategoryID      uint
+	Category        GraphCategory `cascade:"none"`
+}
+
+type GraphLanguage struct {
+	ID   uint
+	Name string
+}
+
+type GraphEmployee struct {
+	data.LazyLoader `gorm:"-"`
+	ID              uint
+	Name            string
+	CompanyID       uint
+	Company         GraphCompany
+	Card            GraphCard
+	Manages         []GraphProduct
+	Languages       []GraphLanguage `gorm:"many2many:graph_employee_languages"`
+}
+
+func TestGormRepository_CreateGraph(t *testing.T) {
+	db := getGormDB()
+	db.AutoMigrate(&GraphCompany{}, &GraphCategory{}, &GraphCard{}, &GraphProduct{}, &GraphLanguage{}, &GraphEmployee{})
+	var tables []string
+	db.Callback().Create().After("gorm:create").Register("test:capture_create_table", func(tx *gorm.DB) {
+		tables = append(tables, tx.Statement.Table)
+	})
+
+	transactionManager := data.NewGormTransactionManager(db)
+	employeeRepository := data.NewGormRepository[GraphEmployee, uint](transactionManager)
+
+	english := GraphLanguage{Name: "english"}
+	banking := GraphCategory{Name: "banking"}
+	db.Create(&english)
+	db.Create(&banking)
+	// deposit managed by ryan is moved to the created employee
+	ryan := GraphEmployee{Name: "ryan", Company: GraphCompany{Name: "kakao pay"}}
+	db.Create(&ryan)
+	deposit := GraphProduct{Name: "deposit", GraphEmployeeID: ryan.ID, CompanyID: ryan.CompanyID, CategoryID: banking.ID}
+	db.Create(&deposit)
+
+	ctx := context.Background()
+	t.Run("creates parents, root and children in order", func(t *testing.T) {
+		tables = nil
+		var created GraphEmployee
+		err := transactionManager.Do(ctx, func(ctx context.Context) error {
+			var err error
+			created, err = employeeRepository.Create(ctx, GraphEmployee{
+				Name:    "reuben",
+				Company: GraphCompany{Name: "kakao"},
+				Card:    GraphCard{Number: "1234"},
+				Manages: []GraphProduct{
+					{Name: "loan", Company: GraphCompany{Name: "kakao bank"}, CategoryID: banking.ID, Category: GraphCategory{Name: "ignored"}},
+					deposit,
+				},
+				Languages: []GraphLanguage{english, {Name: "korean"}},
+			})
+			if err != nil {
+				return err
+			}
+
+			// nested entities are loaded ones
+			category, err := data.LazyLoadNow[GraphCategory]("Category", &created.Manages[0])
+			assert.Nil(t, err)
+			assert.Equal(t, banking, category)
+			return nil
+		})
+		assert.Nil(t, err)
+		assert.Equal(t, []string{
+			"graph_companies", "graph_employees", "graph_cards", "graph_companies", "graph_products", "graph_languages", "graph_employee_languages",
+		}, tables)
+
+		assert.NotEmpty(t, created.ID)
+		assert.NotEmpty(t, created.Company.ID)
+		assert.Equal(t, created.Company.ID, created.CompanyID)
+		assert.NotEmpty(t, created.Card.ID)
+		assert.Equal(t, created.ID, created.Card.GraphEmployeeID)
+		loan := created.Manages[0]
+		assert.NotEmpty(t, loan.ID)
+		assert.NotEmpty(t, loan.Company.ID)
+		assert.Equal(t, loan.Company.ID, loan.CompanyID)
+		assert.Equal(t, created.ID, loan.GraphEmployeeID)
+		assert.NotEmpty(t, created.Languages[1].ID)
+
+		var count int64
+		db.Model(&GraphCategory{}).Count(&count)
+		assert.Equal(t, int64(1), count)
+		var employeeID uint
+		db.Model(&GraphProduct{}).Where("id = ?", deposit.ID).Pluck("graph_employee_id", &employeeID)
+		assert.Equal(t, created.ID, employeeID)
+		var languageIDs []uint
+		db.Table("graph_employee_languages").Where("graph_employee_id = ?", created.ID).Order("graph_language_id").Pluck("graph_language_id", &languageIDs)
+		assert.Equal(t, []uint{english.ID, created.Languages[1].ID}, languageIDs)
+	})
+	t.Run("creates new polymorphic owner", func(t *testing.T) {
+		db.AutoMigrate(&PolyProduct{}, &PolyComment{})
+		commentRepository := data.NewGormRepository[PolyComment, uint](transactionManager)
+		err := transactionManager.Do(ctx, func(ctx context.Context) error {
+			created, err := commentRepository.Create(ctx, PolyComment{Body: "fast", Owner: PolyProduct{Name: "storage"}})
+			assert.Nil(t, err)
+			assert.NotEmpty(t, created.OwnerID)
+			assert.Equal(t, "poly_products", created.OwnerType)
+			assert.Equal(t, created.OwnerID, created.Owner.(PolyProduct).ID)
+
+			owner, err := data.LazyLoadNow[Commentable]("Owner", &created)
+			assert.Nil(t, err)
+			assert.Equal(t, "product storage", owner.OwnerName())
+			return nil
+		})
+		assert.Nil(t, err)
+	})
+}
+
+func TestInMemoryRepository_CreateGraph(t *testing.T) {
+	dummyTransactionManager := data.NewDummyTransactionManager()
+	employeeRepository := data.NewInMemoryRepository[GraphEmployee, uint](dummyTransactionManager)
+
+	ctx := context.Background()
+	dummyTransactionManager.Do(ctx, func(ctx context.Context) error {
+		existing, err := employeeRepository.Create(ctx, GraphEmployee{ID: 1, Name: "ryan"})
+		assert.Nil(t, err)
+		assert.Equal(t, uint(1), existing.ID)
+
+		created, err := employeeRepository.Create(ctx, GraphEmployee{
+			Name:      "reuben",
+			Company:   GraphCompany{Name: "kakao"},
+			Card:      GraphCard{Number: "1234"},
+			Manages:   []GraphProduct{{Name: "loan", Company: GraphCompany{Name: "kakao bank"}, Category: GraphCategory{Name: "ignored"}}},
+			Languages: []GraphLanguage{{Name: "korean"}},
+		})
+		assert.Nil(t, err)
+		assert.Equal(t, uint(2), created.ID)
+		assert.Equal(t, uint(1), created.Company.ID)
+		assert.Equal(t, created.Company.ID, created.CompanyID)
+		assert.Equal(t, uint(1), created.Card.ID)
+		assert.Equal(t, created.ID, created.Card.GraphEmployeeID)
+		loan := created.Manages[0]
+		assert.Equal(t, uint(1), loan.ID)
+		assert.Equal(t, uint(2), loan.Company.ID)
+		assert.Equal(t, loan.Company.ID, loan.CompanyID)
+		assert.Equal(t, created.ID, loan.GraphEmployeeID)
+		assert.Empty(t, loan.Category.ID)
+		assert.Equal(t, uint(1), created.Languages[0].ID)
+
+		found, err := employeeRepository.FindOne(ctx, created.ID)
+		assert.Nil(t, err)
+		assert.Equal(t, created, found)
+
+		employees, err := employeeRepository.FindBy(ctx, "Company", created.Company)
+		assert.Nil(t, err)
+		assert.Equal(t, 1, len(employees))
+		return nil
+	})
+}
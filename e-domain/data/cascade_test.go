@@ -0,0 +1,117 @@
+package data_test
+
+import (
+	"context"
+	"github.com/reuben-baek/go-learning/e-domain/data"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+type CascadeLine struct {
+	ID             uint
+	Product        string
+	CascadeOrderID *uint
+}
+
+type CascadeItem struct {
+	ID             uint
+	Product        string
+	CascadeOrderID *uint
+}
+
+type CascadeNote struct {
+	ID             uint
+	Text           string
+	CascadeOrderID *uint
+}
+
+type CascadeOrder struct {
+	ID    uint
+	Name  string
+	Lines []CascadeLine `gorm:"foreignKey:CascadeOrderID" cascade:"persist,merge"`
+	Items []CascadeItem `gorm:"foreignKey:CascadeOrderID"`
+	Notes []CascadeNote `gorm:"foreignKey:CascadeOrderID" cascade:"none"`
+}
+
+func TestGormRepository_Cascade(t *testing.T) {
+	db := getGormDB()
+	db.AutoMigrate(&CascadeOrder{}, &CascadeLine{}, &CascadeItem{}, &CascadeNote{})
+
+	transactionManager := data.NewGormTransactionManager(db)
+	orderRepository := data.NewGormRepository[CascadeOrder, uint](transactionManager)
+
+	count := func(entity any, conditions ...any) int64 {
+		var count int64
+		db.Model(entity).Where(conditions[0], conditions[1:]...).Count(&count)
+		return count
+	}
+
+	ctx := context.Background()
+	var order CascadeOrder
+	t.Run("create persists associations with persist", func(t *testing.T) {
+		err := transactionManager.Do(ctx, func(ctx context.Context) error {
+			var err error
+			order, err = orderRepository.Create(ctx, CascadeOrder{
+				Name:  "order-1",
+				Lines: []CascadeLine{{Product: "ssd"}, {Product: "hdd"}},
+				Items: []CascadeItem{{Product: "ssd"}, {Product: "hdd"}},
+				Notes: []CascadeNote{{Text: "fragile"}},
+			})
+			return err
+		})
+		assert.Nil(t, err)
+		assert.Equal(t, int64(2), count(&CascadeLine{}, "cascade_order_id = ?", order.ID))
+		assert.Equal(t, int64(2), count(&CascadeItem{}, "cascade_order_id = ?", order.ID))
+		assert.Equal(t, int64(0), count(&CascadeNote{}, "1 = 1"))
+	})
+	t.Run("update merges associations with merge, and removes orphans with orphan removal", func(t *testing.T) {
+		note := CascadeNote{Text: "urgent", CascadeOrderID: &order.ID}
+		db.Create(&note)
+
+		update := order
+		update.Lines = order.Lines[:1]
+		update.Items = order.Items[:1]
+		update.Notes = nil
+		err := transactionManager.Do(ctx, func(ctx context.Context) error {
+			_, err := orderRepository.Update(ctx, update)
+			return err
+		})
+		assert.Nil(t, err)
+		assert.Equal(t, int64(1), count(&CascadeLine{}, "cascade_order_id = ?", order.ID))
+		assert.Equal(t, int64(1), count(&CascadeLine{}, "cascade_order_id is null"), "unlinked")
+		assert.Equal(t, int64(1), count(&CascadeItem{}, "cascade_order_id = ?", order.ID))
+		assert.Equal(t, int64(1), count(&CascadeItem{}, "1 = 1"), "orphan removed")
+		assert.Equal(t, int64(1), count(&CascadeNote{}, "cascade_order_id = ?", order.ID), "not merged")
+	})
+	t.Run("delete removes associations with remove, and unlinks the others", func(t *testing.T) {
+		db.Delete(&CascadeNote{}, "1 = 1")
+		err := transactionManager.Do(ctx, func(ctx context.Context) error {
+			return orderRepository.Delete(ctx, order)
+		})
+		assert.Nil(t, err)
+		assert.Equal(t, int64(2), count(&CascadeLine{}, "cascade_order_id is null"))
+		assert.Equal(t, int64(0), count(&CascadeItem{}, "1 = 1"))
+	})
+	t.Run("validate", func(t *testing.T) {
+		type Wrong struct {
+			ID    uint
+			Lines []CascadeLine `cascade:"persist,none"`
+		}
+		type WrongBelongTo struct {
+			ID            uint
+			CascadeLineID uint
+			CascadeLine   CascadeLine `cascade:"remove"`
+		}
+		type WrongManyToMany struct {
+			ID    uint
+			Notes []CascadeNote `cascade:"orphan_removal"`
+		}
+		assert.Nil(t, data.ValidateMappings(CascadeOrder{}))
+		assert.ErrorContains(t, data.ValidateMappings(&Wrong{}), "none of Wrong.Lines is combined with other options")
+		assert.ErrorContains(t, data.ValidateMappings(WrongBelongTo{}), "belong-to WrongBelongTo.CascadeLine cascades persist only")
+		assert.ErrorContains(t, data.ValidateMappings([]WrongManyToMany{}), "many-to-many WrongManyToMany.Notes can not remove orphans")
+		assert.Panics(t, func() {
+			data.NewGormRepository[Wrong, uint](transactionManager)
+		})
+	})
+}
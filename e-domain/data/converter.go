@@ -0,0 +1,281 @@
+package data
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"gorm.io/gorm/schema"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// AttributeConverter converts an attribute of entities to a column value, and back.
+// A field selects a registered converter by name with `gorm:"serializer:<name>"`, and GormRepository applies it
+// on read and write, and to values of FindByValue.
+type AttributeConverter interface {
+	// ToColumn converts attribute, the value of a field, to a column value.
+	ToColumn(attribute any) (any, error)
+	// ToAttribute converts column, read from the database, to a value of attributeType.
+	ToAttribute(column any, attributeType reflect.Type) (any, error)
+}
+
+// converters are registered attribute converters by name. Built-ins are
+//
+//	enum:      values of enum types registered by RegisterEnum, stored by String()
+//	conv_json: any value stored as JSON, as GORM's json serializer
+//	csv:       slices of strings stored as comma separated values
+//	duration:  time.Duration stored as nanoseconds, read also from texts such as 1h30m0s
+//	utc:       time.Time stored in UTC, normalizing time zones
+//
+// GORM migrates columns by the kind of fields, so enums of integer kinds declare a text column by `type:`, such as
+// `gorm:"serializer:enum;type:varchar(16)"`.
+var converters = struct {
+	m          sync.RWMutex
+	converters map[string]AttributeConverter
+}{
+	converters: make(map[string]AttributeConverter),
+}
+
+func init() {
+	RegisterConverter("enum", enumConverter{})
+	RegisterConverter("conv_json", jsonConverter{})
+	RegisterConverter("csv", csvConverter{})
+	RegisterConverter("duration", durationConverter{})
+	RegisterConverter("utc", utcConverter{})
+}
+
+// RegisterConverter registers converter by name, replacing the converter of the same name.
+// It panics if name is of a GORM serializer, such as json, which is used by fields of other packages too.
+// Converters should be registered before repositories of entities using them are created.
+func RegisterConverter(name string, converter AttributeConverter) {
+	converters.m.Lock()
+	defer converters.m.Unlock()
+	if _, ok := converters.converters[strings.ToLower(name)]; !ok {
+		if _, ok := schema.GetSerializer(name); ok {
+			panic(fmt.Sprintf("RegisterConverter: %s is a registered serializer of GORM", name))
+		}
+	}
+	converters.converters[strings.ToLower(name)] = converter
+	schema.RegisterSerializer(name, converterSerializer{converter: converter})
+}
+
+func converterOf(name string) (AttributeConverter, bool) {
+	converters.m.RLock()
+	defer converters.m.RUnlock()
+	converter, ok := converters.converters[strings.ToLower(name)]
+	return converter, ok
+}
+
+// ConvertToColumn converts attribute to a column value by the converter named name, to be a parameter of queries.
+func ConvertToColumn(name string, attribute any) (any, error) {
+	converter, ok := converterOf(name)
+	if !ok {
+		return nil, fmt.Errorf("ConvertToColumn: converter %s is not registered", name)
+	}
+	return converter.ToColumn(attribute)
+}
+
+// mustHaveSerializer panics if the serializer of field, a converter or GORM serializer, is not registered.
+func mustHaveSerializer(entityType reflect.Type, field reflect.StructField) {
+	name, ok := schema.ParseTagSetting(field.Tag.Get("gorm"), ";")["SERIALIZER"]
+	if !ok {
+		return
+	}
+	if _, ok := schema.GetSerializer(name); !ok {
+		panic(fmt.Sprintf("serializer: %s of %s.%s is not registered", name, entityType.Name(), field.Name))
+	}
+}
+
+// converterSerializer adapts AttributeConverter to a serializer of GORM.
+type converterSerializer struct {
+	converter AttributeConverter
+}
+
+func (s converterSerializer) Scan(ctx context.Context, field *schema.Field, dst reflect.Value, dbValue any) error {
+	fieldValue := reflect.Zero(field.FieldType)
+	if dbValue != nil {
+		attribute, err := s.converter.ToAttribute(dbValue, field.FieldType)
+		if err != nil {
+			return fmt.Errorf("%s.%s: %w", field.Schema.Name, field.Name, err)
+		}
+		fieldValue = reflect.ValueOf(attribute)
+		if !fieldValue.Type().ConvertibleTo(field.FieldType) {
+			return fmt.Errorf("%s.%s: %s is not convertible to %s", field.Schema.Name, field.Name, fieldValue.Type(), field.FieldType)
+		}
+		fieldValue = fieldValue.Convert(field.FieldType)
+	}
+	field.ReflectValueOf(ctx, dst).Set(fieldValue)
+	return nil
+}
+
+func (s converterSerializer) Value(ctx context.Context, field *schema.Field, dst reflect.Value, fieldValue any) (any, error) {
+	return s.converter.ToColumn(fieldValue)
+}
+
+// columnText returns the text of a column value read as string or []byte.
+func columnText(column any) (string, error) {
+	switch v := column.(type) {
+	case string:
+		return v, nil
+	case []byte:
+		return string(v), nil
+	}
+	return "", fmt.Errorf("column value %v is %T, not text", column, column)
+}
+
+// enums maps String() of enum values to the values by enum type.
+var enums = struct {
+	m      sync.RWMutex
+	values map[reflect.Type]map[string]any
+}{
+	values: make(map[reflect.Type]map[string]any),
+}
+
+// RegisterEnum registers values of an enum type for the enum converter, which stores them by String().
+func RegisterEnum[E fmt.Stringer](values ...E) {
+	enums.m.Lock()
+	defer enums.m.Unlock()
+	enumType := reflect.TypeOf((*E)(nil)).Elem()
+	if enums.values[enumType] == nil {
+		enums.values[enumType] = make(map[string]any)
+	}
+	for _, value := range values {
+		enums.values[enumType][value.String()] = value
+	}
+}
+
+type enumConverter struct{}
+
+func (enumConverter) ToColumn(attribute any) (any, error) {
+	stringer, ok := attribute.(fmt.Stringer)
+	if !ok {
+		return nil, fmt.Errorf("enum %T is not fmt.Stringer", attribute)
+	}
+	return stringer.String(), nil
+}
+
+func (enumConverter) ToAttribute(column any, attributeType reflect.Type) (any, error) {
+	text, err := columnText(column)
+	if err != nil {
+		return nil, err
+	}
+	enums.m.RLock()
+	defer enums.m.RUnlock()
+	values, ok := enums.values[attributeType]
+	if !ok {
+		return nil, fmt.Errorf("enum %s is not registered", attributeType)
+	}
+	value, ok := values[text]
+	if !ok {
+		return nil, fmt.Errorf("enum %s has no value %s", attributeType, text)
+	}
+	return value, nil
+}
+
+type jsonConverter struct{}
+
+func (jsonConverter) ToColumn(attribute any) (any, error) {
+	bytes, err := json.Marshal(attribute)
+	return string(bytes), err
+}
+
+func (jsonConverter) ToAttribute(column any, attributeType reflect.Type) (any, error) {
+	text, err := columnText(column)
+	if err != nil {
+		return nil, err
+	}
+	attribute := reflect.New(attributeType)
+	if text != "" {
+		if err := json.Unmarshal([]byte(text), attribute.Interface()); err != nil {
+			return nil, err
+		}
+	}
+	return attribute.Elem().Interface(), nil
+}
+
+type csvConverter struct{}
+
+func (csvConverter) ToColumn(attribute any) (any, error) {
+	value := reflect.ValueOf(attribute)
+	if value.Kind() != reflect.Slice || value.Type().Elem().Kind() != reflect.String {
+		return nil, fmt.Errorf("csv %T is not a slice of strings", attribute)
+	}
+	texts := make([]string, value.Len())
+	for i := range texts {
+		texts[i] = value.Index(i).String()
+		if strings.Contains(texts[i], ",") {
+			return nil, fmt.Errorf("csv element '%s' contains comma", texts[i])
+		}
+	}
+	return strings.Join(texts, ","), nil
+}
+
+func (csvConverter) ToAttribute(column any, attributeType reflect.Type) (any, error) {
+	text, err := columnText(column)
+	if err != nil {
+		return nil, err
+	}
+	if attributeType.Kind() != reflect.Slice || attributeType.Elem().Kind() != reflect.String {
+		return nil, fmt.Errorf("csv %s is not a slice of strings", attributeType)
+	}
+	if text == "" {
+		return reflect.Zero(attributeType).Interface(), nil
+	}
+	texts := strings.Split(text, ",")
+	attribute := reflect.MakeSlice(attributeType, len(texts), len(texts))
+	for i, t := range texts {
+		attribute.Index(i).SetString(t)
+	}
+	return attribute.Interface(), nil
+}
+
+type durationConverter struct{}
+
+func (durationConverter) ToColumn(attribute any) (any, error) {
+	duration, ok := attribute.(time.Duration)
+	if !ok {
+		return nil, fmt.Errorf("duration %T is not time.Duration", attribute)
+	}
+	return int64(duration), nil
+}
+
+func (durationConverter) ToAttribute(column any, attributeType reflect.Type) (any, error) {
+	if nanoseconds, ok := column.(int64); ok {
+		return time.Duration(nanoseconds), nil
+	}
+	text, err := columnText(column)
+	if err != nil {
+		return nil, err
+	}
+	if nanoseconds, err := strconv.ParseInt(text, 10, 64); err == nil {
+		return time.Duration(nanoseconds), nil
+	}
+	// text columns of String(), such as 1h30m0s
+	return time.ParseDuration(text)
+}
+
+type utcConverter struct{}
+
+func (utcConverter) ToColumn(attribute any) (any, error) {
+	t, ok := attribute.(time.Time)
+	if !ok {
+		return nil, fmt.Errorf("utc %T is not time.Time", attribute)
+	}
+	return t.UTC(), nil
+}
+
+func (utcConverter) ToAttribute(column any, attributeType reflect.Type) (any, error) {
+	if t, ok := column.(time.Time); ok {
+		return t.UTC(), nil
+	}
+	// text columns of RFC 3339
+	text, err := columnText(column)
+	if err != nil {
+		return nil, err
+	}
+	t, err := time.Parse(time.RFC3339Nano, text)
+	return t.UTC(), err
+}
@@ -0,0 +1,146 @@
+package data_test
+
+import (
+	"context"
+	"fmt"
+	"github.com/reuben-baek/go-learning/e-domain/data"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm/schema"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+type TaskStatus int
+
+const (
+	TaskTodo TaskStatus = iota + 1
+	TaskDoing
+	TaskDone
+)
+
+func (s TaskStatus) String() string {
+	switch s {
+	case TaskTodo:
+		return "todo"
+	case TaskDoing:
+		return "doing"
+	case TaskDone:
+		return "done"
+	}
+	return fmt.Sprintf("TaskStatus(%d)", int(s))
+}
+
+// upperConverter stores strings in upper case.
+type upperConverter struct{}
+
+func (upperConverter) ToColumn(attribute any) (any, error) {
+	return strings.ToUpper(attribute.(string)), nil
+}
+
+func (upperConverter) ToAttribute(column any, attributeType reflect.Type) (any, error) {
+	return strings.ToLower(column.(string)), nil
+}
+
+type ConvertedTask struct {
+	ID      uint
+	Title   string            `gorm:"serializer:upper"`
+	Status  TaskStatus        `gorm:"serializer:enum;type:varchar(16)"`
+	Tags    []string          `gorm:"serializer:csv"`
+	Timeout time.Duration     `gorm:"serializer:duration"`
+	DueAt   time.Time         `gorm:"serializer:utc"`
+	Labels  map[string]string `gorm:"serializer:conv_json"`
+}
+
+func init() {
+	data.RegisterEnum(TaskTodo, TaskDoing, TaskDone)
+	data.RegisterConverter("upper", upperConverter{})
+}
+
+func TestGormRepository_Converter(t *testing.T) {
+	db := getGormDB()
+	db.AutoMigrate(&ConvertedTask{})
+
+	transactionManager := data.NewGormTransactionManager(db)
+	taskRepository := data.NewGormRepository[ConvertedTask, uint](transactionManager)
+
+	seoul := time.FixedZone("KST", 9*60*60)
+	dueAt := time.Date(2024, 3, 1, 9, 0, 0, 0, seoul)
+
+	ctx := context.Background()
+	var task ConvertedTask
+	t.Run("write and read", func(t *testing.T) {
+		err := transactionManager.Do(ctx, func(ctx context.Context) error {
+			var err error
+			task, err = taskRepository.Create(ctx, ConvertedTask{
+				Title:   "review",
+				Status:  TaskDoing,
+				Tags:    []string{"go", "gorm"},
+				Timeout: 90 * time.Minute,
+				DueAt:   dueAt,
+				Labels:  map[string]string{"team": "storage"},
+			})
+			taskRepository.Create(ctx, ConvertedTask{Title: "release", Status: TaskTodo})
+			return err
+		})
+		assert.Nil(t, err)
+
+		var columns map[string]any
+		db.Table("converted_tasks").Where("id = ?", task.ID).Take(&columns)
+		assert.Equal(t, "REVIEW", columns["title"])
+		assert.Equal(t, "doing", columns["status"])
+		assert.Equal(t, "go,gorm", columns["tags"])
+		assert.Equal(t, int64(90*time.Minute), columns["timeout"])
+		assert.Equal(t, time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC), columns["due_at"])
+		assert.Equal(t, `{"team":"storage"}`, columns["labels"])
+
+		found, err := taskRepository.FindOne(ctx, task.ID)
+		assert.Nil(t, err)
+		assert.Equal(t, "review", found.Title)
+		assert.Equal(t, TaskDoing, found.Status)
+		assert.Equal(t, []string{"go", "gorm"}, found.Tags)
+		assert.Equal(t, 90*time.Minute, found.Timeout)
+		assert.True(t, dueAt.Equal(found.DueAt))
+		assert.Equal(t, time.UTC, found.DueAt.Location())
+		assert.Equal(t, map[string]string{"team": "storage"}, found.Labels)
+	})
+	t.Run("query parameters", func(t *testing.T) {
+		tasks, err := taskRepository.FindByValue(ctx, "Status", TaskTodo)
+		assert.Nil(t, err)
+		assert.Equal(t, 1, len(tasks))
+		assert.Equal(t, "release", tasks[0].Title)
+
+		tasks, err = taskRepository.FindByValue(ctx, "Tags", []string{"go", "gorm"})
+		assert.Nil(t, err)
+		assert.Equal(t, 1, len(tasks))
+
+		status, err := data.ConvertToColumn("enum", TaskDoing)
+		assert.Nil(t, err)
+		var count int64
+		db.Model(&ConvertedTask{}).Where("status = ?", status).Count(&count)
+		assert.Equal(t, int64(1), count)
+
+		_, err = data.ConvertToColumn("unknown", TaskDoing)
+		assert.ErrorContains(t, err, "converter unknown is not registered")
+	})
+	t.Run("errors", func(t *testing.T) {
+		db.Table("converted_tasks").Where("id = ?", task.ID).Update("status", "canceled")
+		_, err := taskRepository.FindOne(ctx, task.ID)
+		assert.ErrorContains(t, err, "enum data_test.TaskStatus has no value canceled")
+
+		type WrongSerializer struct {
+			ID     uint
+			Status TaskStatus `gorm:"serializer:unknown"`
+		}
+		assert.ErrorContains(t, data.ValidateMappings(WrongSerializer{}), "serializer: unknown of WrongSerializer.Status is not registered")
+	})
+	t.Run("serializers of GORM are kept", func(t *testing.T) {
+		assert.PanicsWithValue(t, "RegisterConverter: json is a registered serializer of GORM", func() {
+			data.RegisterConverter("json", upperConverter{})
+		})
+		serializer, ok := schema.GetSerializer("json")
+		assert.True(t, ok)
+		assert.IsType(t, schema.JSONSerializer{}, serializer)
+	})
+}
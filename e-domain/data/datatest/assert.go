@@ -0,0 +1,31 @@
+// Package datatest provides test helpers asserting SQL statements issued by repositories.
+// The *gorm.DB of repositories should be registered by data.RegisterSQLStatistics or data.WithStatistics.
+package datatest
+
+import (
+	"context"
+	"github.com/reuben-baek/go-learning/e-domain/data"
+	"strings"
+	"testing"
+)
+
+// AssertQueryCount runs f with a context collecting SQL statistics, and fails t unless f issues expected statements.
+func AssertQueryCount(t testing.TB, ctx context.Context, expected int, f func(ctx context.Context)) *data.SQLStatistics {
+	t.Helper()
+	ctx, stats := data.WithSQLStatistics(ctx)
+	f(ctx)
+	if count := stats.Count(); count != expected {
+		t.Errorf("expected %d statements, but %d statements are issued:\n%s", expected, count, strings.Join(stats.Statements(), "\n"))
+	}
+	return stats
+}
+
+// AssertNoNPlusOne fails t if a statement shape of stats is issued threshold times or more.
+func AssertNoNPlusOne(t testing.TB, stats *data.SQLStatistics, threshold int) bool {
+	t.Helper()
+	repeated := stats.Repeated(threshold)
+	for _, statement := range repeated {
+		t.Errorf("N+1 queries, issued %d times: %s", statement.Count, statement.Shape)
+	}
+	return len(repeated) == 0
+}
@@ -0,0 +1,68 @@
+package datatest_test
+
+import (
+	"context"
+	"fmt"
+	"github.com/reuben-baek/go-learning/e-domain/data"
+	"github.com/reuben-baek/go-learning/e-domain/data/datatest"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"path/filepath"
+	"testing"
+)
+
+type Company struct {
+	ID   uint
+	Name string
+}
+
+// recorder records failures instead of failing the test.
+type recorder struct {
+	testing.TB
+	errors []string
+}
+
+func (r *recorder) Helper() {}
+
+func (r *recorder) Errorf(format string, args ...any) {
+	r.errors = append(r.errors, fmt.Sprintf(format, args...))
+}
+
+func TestAssertions(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open(filepath.Join(t.TempDir(), "datatest.db")), &gorm.Config{})
+	if err != nil {
+		panic("failed to connect database")
+	}
+	db.AutoMigrate(&Company{})
+
+	transactionManager := data.NewGormTransactionManager(db, data.WithStatistics())
+	companyRepository := data.NewGormRepository[Company, uint](transactionManager)
+	ctx := context.Background()
+	kakao, _ := companyRepository.Create(ctx, Company{Name: "kakao"})
+
+	findTwice := func(ctx context.Context) {
+		companyRepository.FindOne(ctx, kakao.ID)
+		companyRepository.FindOne(ctx, kakao.ID)
+	}
+
+	t.Run("query count", func(t *testing.T) {
+		r := &recorder{TB: t}
+		stats := datatest.AssertQueryCount(r, ctx, 2, findTwice)
+		assert.Empty(t, r.errors)
+		assert.Equal(t, 2, stats.Count())
+
+		datatest.AssertQueryCount(r, ctx, 1, findTwice)
+		assert.Equal(t, 1, len(r.errors))
+		assert.Contains(t, r.errors[0], "expected 1 statements, but 2 statements are issued")
+	})
+	t.Run("n+1", func(t *testing.T) {
+		r := &recorder{TB: t}
+		stats := datatest.AssertQueryCount(r, ctx, 2, findTwice)
+		assert.True(t, datatest.AssertNoNPlusOne(r, stats, 3))
+		assert.Empty(t, r.errors)
+
+		assert.False(t, datatest.AssertNoNPlusOne(r, stats, 2))
+		assert.Equal(t, []string{"N+1 queries, issued 2 times: SELECT * FROM `companies` WHERE id = ? ORDER BY `companies`.`id` LIMIT 1"}, r.errors)
+	})
+}
@@ -64,3 +64,22 @@ func (d *DtoWrapFindByRepository[D, M, E, S]) FindBy(ctx context.Context, name s
 	}
 	return models, err
 }
+
+// DtoWrapFindByValueRepository finds models by a value object, which models and DTOs share as the same type.
+type DtoWrapFindByValueRepository[D DTO[M], M any] struct {
+	dtoRepository FindByValueRepository[D]
+}
+
+func NewDtoWrapFindByValueRepository[D DTO[M], M any](dtoRepository FindByValueRepository[D]) *DtoWrapFindByValueRepository[D, M] {
+	return &DtoWrapFindByValueRepository[D, M]{dtoRepository: dtoRepository}
+}
+
+func (d *DtoWrapFindByValueRepository[D, M]) FindByValue(ctx context.Context, name string, value any) ([]M, error) {
+	dtos, err := d.dtoRepository.FindByValue(ctx, name, value)
+
+	models := make([]M, 0, len(dtos))
+	for _, v := range dtos {
+		models = append(models, v.To())
+	}
+	return models, err
+}
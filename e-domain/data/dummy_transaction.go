@@ -18,7 +18,36 @@ type dummyTransactionKey struct{}
 func (d *dummyTransactionManager) Do(ctx context.Context, f func(ctx context.Context) error) error {
 	transactionID := uuid.New()
 	logrus.Infof("DummyTransactionManager.Do: transaction [%s]", transactionID)
-	return f(context.WithValue(ctx, dummyTransactionKey{}, &transactionID))
+	txCtx := ctx
+	if timeout := transactionTimeout(ctx, 0); timeout > 0 {
+		var cancel context.CancelFunc
+		txCtx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+	newCtx, synchronization := withTransactionSynchronization(context.WithValue(txCtx, dummyTransactionKey{}, &transactionID))
+
+	panicked := true
+	defer func() {
+		if panicked {
+			synchronization.triggerAfterRollback(ctx)
+		}
+	}()
+
+	err := f(newCtx)
+	if err == nil {
+		err = synchronization.triggerBeforeCommit(newCtx)
+	}
+	if err == nil {
+		err = txCtx.Err()
+	}
+	panicked = false
+
+	if err != nil {
+		synchronization.triggerAfterRollback(ctx)
+		return err
+	}
+	synchronization.triggerAfterCommit(ctx)
+	return nil
 }
 
 func (d *dummyTransactionManager) Get(ctx context.Context) any {
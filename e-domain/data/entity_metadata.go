@@ -0,0 +1,248 @@
+package data
+
+import (
+	"fmt"
+	"gorm.io/gorm/schema"
+	"reflect"
+	"sync"
+)
+
+// entityMetadata is reflection result of an entity struct type, parsed once and cached in metadataRegistry.
+type entityMetadata struct {
+	entityType   reflect.Type
+	fields       map[string][]int // field index by name, including promoted fields
+	associations []associationMetadata
+	valueObjects map[string]bool // value object fields by name, which are not associations
+}
+
+// associationMetadata is Association without values of an entity, which are set by entityMetadata.associationsOf.
+type associationMetadata struct {
+	Association
+	entityType    reflect.Type // type of PtrToEntity element
+	belongToField string       // belong-to foreign key field of the entity
+	cascade       CascadeType
+}
+
+// metadataRegistry caches *entityMetadata by reflect.Type. It is safe for concurrent use.
+var metadataRegistry sync.Map
+
+// metadataOf returns metadata of structType, parsing it at the first call.
+func metadataOf(structType reflect.Type) *entityMetadata {
+	if metadata, ok := metadataRegistry.Load(structType); ok {
+		return metadata.(*entityMetadata)
+	}
+	metadata, _ := metadataRegistry.LoadOrStore(structType, parseEntityMetadata(structType))
+	return metadata.(*entityMetadata)
+}
+
+func parseEntityMetadata(entityType reflect.Type) *entityMetadata {
+	metadata := &entityMetadata{entityType: entityType, fields: make(map[string][]int), valueObjects: make(map[string]bool)}
+	for _, field := range reflect.VisibleFields(entityType) {
+		if resolved, ok := entityType.FieldByName(field.Name); ok {
+			metadata.fields[field.Name] = resolved.Index
+		}
+	}
+
+	for _, field := range declaredFields(entityType) {
+		if isSystemStructType(field.Type) {
+			continue
+		}
+		if isValueObjectField(field) {
+			mustHaveSerializer(entityType, field)
+			metadata.valueObjects[field.Name] = true
+			continue
+		}
+		var association associationMetadata
+		if tagged, ok := parseAssocTag(entityType, field); ok {
+			association = taggedAssociation(entityType, field, tagged)
+		} else if association, ok = conventionalAssociation(entityType, field); !ok {
+			continue
+		}
+		association.cascade = parseCascadeTag(entityType, field, association.Type)
+		metadata.associations = append(metadata.associations, association)
+	}
+	return metadata
+}
+
+// declaredFields returns fields of structType, and fields of anonymous structs embedded in it, which GORM maps to
+// columns of structType, such as the root struct of inheritance subtypes.
+func declaredFields(structType reflect.Type) []reflect.StructField {
+	var fields []reflect.StructField
+	for i := 0; i < structType.NumField(); i++ {
+		field := structType.Field(i)
+		if field.Anonymous && field.Type.Kind() == reflect.Struct && !isSystemStructType(field.Type) && !isValueObjectField(field) {
+			fields = append(fields, declaredFields(field.Type)...)
+			continue
+		}
+		fields = append(fields, field)
+	}
+	return fields
+}
+
+// field returns the named field of value, whose type is the entity type.
+func (m *entityMetadata) field(value reflect.Value, name string) (reflect.Value, bool) {
+	index, ok := m.fields[name]
+	if !ok {
+		return reflect.Value{}, false
+	}
+	field, err := value.FieldByIndexErr(index)
+	if err != nil {
+		return reflect.Value{}, false
+	}
+	return field, true
+}
+
+// associationsOf returns associations of ptrToEntity with new PtrToEntity, and ID of belong-to ones.
+func (m *entityMetadata) associationsOf(ptrToEntity any) []Association {
+	if len(m.associations) == 0 {
+		return nil
+	}
+	associations := make([]Association, len(m.associations))
+	for i, metadata := range m.associations {
+		associations[i] = metadata.Association
+		if isPolymorphicBelongTo(metadata.Association) {
+			// the entity type is decided by the type field of each entity
+			if entityType, ok := polymorphicTypeOf(findIDValue(ptrToEntity, metadata.Polymorphic+"Type")); ok {
+				associations[i].PtrToEntity = reflect.New(entityType).Interface()
+			}
+		} else {
+			associations[i].PtrToEntity = reflect.New(metadata.entityType).Interface()
+		}
+		if metadata.Type == BelongTo {
+			associations[i].ID = findIDValue(ptrToEntity, metadata.belongToField)
+		}
+	}
+	return associations
+}
+
+func conventionalAssociation(entityType reflect.Type, field reflect.StructField) (associationMetadata, bool) {
+	var association associationMetadata
+	association.Name = field.Name
+	association.FetchMode = ToFetchMode(field.Tag.Get("fetch"))
+
+	belongToForeignKey := fmt.Sprintf("%sID", field.Name)
+	hasForeignKey := fmt.Sprintf("%sID", entityType.Name())
+	if polymorphic, ok := schema.ParseTagSetting(field.Tag.Get("gorm"), ";")["POLYMORPHIC"]; ok {
+		// has-one or has-many by GORM polymorphic tag
+		association.Polymorphic = polymorphic
+		if field.Type.Kind() == reflect.Slice {
+			association.Type = HasMany
+		} else {
+			association.Type = HasOne
+		}
+		return taggedAssociation(entityType, field, association.Association), true
+	}
+	if field.Type.Kind() == reflect.Interface {
+		// polymorphic belong-to by <Field>ID and <Field>Type fields
+		_, hasID := entityType.FieldByName(belongToForeignKey)
+		_, hasType := entityType.FieldByName(field.Name + "Type")
+		if !hasID || !hasType {
+			return association, false
+		}
+		association.Type = BelongTo
+		association.Polymorphic = field.Name
+		association.entityType = field.Type
+		association.belongToField = belongToForeignKey
+		return association, true
+	}
+	if field.Type.Kind() == reflect.Struct || (field.Type.Kind() == reflect.Pointer && field.Type.Elem().Kind() == reflect.Struct) {
+		association.entityType = field.Type
+		if field.Type.Kind() == reflect.Pointer {
+			association.entityType = field.Type.Elem()
+		}
+		if _, ok := entityType.FieldByName(belongToForeignKey); ok {
+			association.Type = BelongTo
+			association.belongToField = belongToForeignKey
+		} else if _, ok := association.entityType.FieldByName(hasForeignKey); ok {
+			association.Type = HasOne
+			association.ForeignKey = toSnakeCase(hasForeignKey)
+		}
+	} else if field.Type.Kind() == reflect.Slice && field.Type.Elem().Kind() == reflect.Struct {
+		association.entityType = field.Type
+		if _, ok := field.Type.Elem().FieldByName(hasForeignKey); ok {
+			association.Type = HasMany
+		} else {
+			association.Type = ManyToMany
+		}
+		association.ForeignKey = toSnakeCase(hasForeignKey)
+	} else {
+		return association, false
+	}
+	return association, true
+}
+
+func taggedAssociation(entityType reflect.Type, field reflect.StructField, tagged Association) associationMetadata {
+	association := associationMetadata{Association: tagged}
+	association.Name = field.Name
+	association.FetchMode = ToFetchMode(field.Tag.Get("fetch"))
+	association.entityType = field.Type
+	if association.entityType.Kind() == reflect.Pointer {
+		association.entityType = association.entityType.Elem()
+	}
+	associationType := association.entityType
+	if associationType.Kind() == reflect.Slice {
+		associationType = associationType.Elem()
+	}
+
+	foreignKeyField := association.foreignKeyField(entityType)
+	if association.Polymorphic != "" {
+		typeField := association.Polymorphic + "Type"
+		holder := associationType
+		if association.Type == BelongTo {
+			holder = entityType
+		}
+		if _, ok := holder.FieldByName(typeField); !ok {
+			panic(fmt.Sprintf("findAssociation: %s has no polymorphic type field %s of %s.%s", holder.Name(), typeField, entityType.Name(), field.Name))
+		}
+	}
+	switch association.Type {
+	case BelongTo:
+		if _, ok := entityType.FieldByName(foreignKeyField); !ok {
+			panic(fmt.Sprintf("findAssociation: %s has no foreign key field %s of %s", entityType.Name(), foreignKeyField, field.Name))
+		}
+		association.belongToField = foreignKeyField
+	case HasOne, HasMany:
+		if _, ok := associationType.FieldByName(foreignKeyField); !ok {
+			panic(fmt.Sprintf("findAssociation: %s has no foreign key field %s of %s.%s", associationType.Name(), foreignKeyField, entityType.Name(), field.Name))
+		}
+		association.ForeignKey = toSnakeCase(foreignKeyField)
+	}
+	return association
+}
+
+// associates reports whether the association of entityValue refers to byEntity, by values in memory.
+// byEntity is referenced by the foreign key for belong-to, and is an element of the association for the others.
+func (a associationMetadata) associates(metadata *entityMetadata, entityValue reflect.Value, byEntity any) bool {
+	if a.Type == BelongTo {
+		foreignKey := findIDValue(entityValue.Interface(), a.belongToField)
+		references := findIDValue(byEntity, a.referencesField())
+		if a.Polymorphic != "" {
+			byType := reflect.Indirect(reflect.ValueOf(byEntity)).Type()
+			value, ok := polymorphicValueOf(byType)
+			typeValue := findIDValue(entityValue.Interface(), a.Polymorphic+"Type")
+			if !ok || typeValue == nil || batchKey(typeValue) != value {
+				return false
+			}
+		}
+		return foreignKey != nil && references != nil && batchKey(foreignKey) == batchKey(references)
+	}
+	byID := findIDValue(byEntity, "ID")
+	field, ok := metadata.field(entityValue, a.Name)
+	if !ok || byID == nil {
+		return false
+	}
+	if field.Kind() != reflect.Slice {
+		field = reflect.Indirect(field)
+		if !field.IsValid() {
+			return false
+		}
+		id := findIDValue(field.Interface(), "ID")
+		return id != nil && batchKey(id) == batchKey(byID)
+	}
+	for i := 0; i < field.Len(); i++ {
+		if id := findIDValue(field.Index(i).Interface(), "ID"); id != nil && batchKey(id) == batchKey(byID) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,102 @@
+package data
+
+import (
+	"github.com/stretchr/testify/assert"
+	"reflect"
+	"sync"
+	"testing"
+)
+
+type metadataCompany struct {
+	ID   uint
+	Name string
+}
+
+type metadataLanguage struct {
+	ID   uint
+	Name string
+}
+
+type metadataCreditCard struct {
+	ID                 uint
+	Number             string
+	MetadataEmployeeID uint
+}
+
+type metadataAddress struct {
+	Street string
+	City   string
+}
+
+type metadataEmployee struct {
+	LazyLoader
+	ID                uint
+	Name              string
+	MetadataCompanyID uint
+	MetadataCompany   metadataCompany
+	Languages         []metadataLanguage   `fetch:"eager"`
+	CreditCards       []metadataCreditCard `fetch:"lazy"`
+	Home              metadataAddress      `gorm:"embedded;embeddedPrefix:home_"`
+	Office            metadataAddress      `gorm:"serializer:json"`
+}
+
+func TestMetadataOf(t *testing.T) {
+	employeeType := reflect.TypeOf(metadataEmployee{})
+
+	var wg sync.WaitGroup
+	parsed := make([]*entityMetadata, 8)
+	for i := range parsed {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			parsed[i] = metadataOf(employeeType)
+		}(i)
+	}
+	wg.Wait()
+	for _, metadata := range parsed {
+		assert.Same(t, parsed[0], metadata)
+	}
+
+	employee := metadataEmployee{ID: 1, MetadataCompanyID: 3}
+	associations := findAssociations(&employee)
+	assert.Equal(t, []string{"MetadataCompany", "Languages", "CreditCards"}, []string{associations[0].Name, associations[1].Name, associations[2].Name})
+	assert.Equal(t, uint(3), associations[0].ID)
+	assert.Equal(t, 3, len(associations), "value objects are not associations")
+	assert.Equal(t, map[string]bool{"Home": true, "Office": true}, parsed[0].valueObjects)
+
+	// PtrToEntity is new for each call, to be a destination of loads
+	assert.NotSame(t, associations[0].PtrToEntity, findAssociations(&employee)[0].PtrToEntity)
+}
+
+func BenchmarkFindAssociations(b *testing.B) {
+	employee := metadataEmployee{ID: 1, MetadataCompanyID: 3}
+	employeeType := reflect.TypeOf(employee)
+	b.Run("parse every call", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			parseEntityMetadata(employeeType).associationsOf(&employee)
+		}
+	})
+	b.Run("cached", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			findAssociations(&employee)
+		}
+	})
+}
+
+func BenchmarkFindIDValue(b *testing.B) {
+	employee := metadataEmployee{ID: 1, MetadataCompanyID: 3}
+	b.Run("FieldByName", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			reflect.ValueOf(&employee).Elem().FieldByName("MetadataCompanyID").Interface()
+		}
+	})
+	b.Run("cached", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			findIDValue(&employee, "MetadataCompanyID")
+		}
+	})
+}
@@ -0,0 +1,92 @@
+package data
+
+import (
+	"context"
+	"reflect"
+	"strings"
+)
+
+// fetchPlan is a tree of association names to fetch eagerly.
+type fetchPlan map[string]fetchPlan
+
+type fetchPlanKey struct{}
+
+type boundFetchPlan struct {
+	entityType reflect.Type // nil until bound by the repository called first
+	paths      []string
+	plan       fetchPlan
+}
+
+// WithFetchPlan returns a context with a fetch plan for the next FindOne or FindBy called with it.
+// paths are association paths of the found entity such as "Manages.Category", which are fetched eagerly
+// in addition to associations eager by fetch tags. Eager tags are kept, as values of them not fetched would be read
+// as removed ones. The other associations are fetched lazily, or in batch if tagged so.
+func WithFetchPlan(ctx context.Context, paths ...string) context.Context {
+	plan := make(fetchPlan)
+	for _, path := range paths {
+		node := plan
+		for _, name := range strings.Split(path, ".") {
+			if _, ok := node[name]; !ok {
+				node[name] = make(fetchPlan)
+			}
+			node = node[name]
+		}
+	}
+	return context.WithValue(ctx, fetchPlanKey{}, &boundFetchPlan{paths: paths, plan: plan})
+}
+
+// bindFetchPlan binds the fetch plan of ctx, if any, to entityType of the repository called with it.
+func bindFetchPlan(ctx context.Context, entityType reflect.Type) context.Context {
+	bound, ok := ctx.Value(fetchPlanKey{}).(*boundFetchPlan)
+	if !ok || bound.entityType != nil {
+		return ctx
+	}
+	return context.WithValue(ctx, fetchPlanKey{}, &boundFetchPlan{entityType: entityType, paths: bound.paths, plan: bound.plan})
+}
+
+// fetchPlanFrom returns the fetch plan of ctx bound to entityType.
+func fetchPlanFrom(ctx context.Context, entityType reflect.Type) (*boundFetchPlan, bool) {
+	bound, ok := ctx.Value(fetchPlanKey{}).(*boundFetchPlan)
+	if !ok || bound.entityType != entityType {
+		return nil, false
+	}
+	return bound, true
+}
+
+// withoutFetchPlan returns a context for loads following the found entities, to which the fetch plan does not apply.
+func withoutFetchPlan(ctx context.Context) context.Context {
+	if _, ok := ctx.Value(fetchPlanKey{}).(*boundFetchPlan); !ok {
+		return ctx
+	}
+	return context.WithValue(ctx, fetchPlanKey{}, nil)
+}
+
+// fetchMode returns fetch mode of association in plan, which is eager if association is in plan, and follows the fetch
+// tag otherwise.
+func (p fetchPlan) fetchMode(association Association) FetchMode {
+	if _, ok := p[association.Name]; ok {
+		return FetchEagerMode
+	}
+	return association.FetchMode
+}
+
+// eagerPaths returns paths of associations eager by fetch tags of entityType and of entities fetched by plan,
+// which are not in plan. prefix is the path of entityType.
+func (p fetchPlan) eagerPaths(entityType reflect.Type, prefix string) []string {
+	var paths []string
+	for _, association := range metadataOf(entityType).associations {
+		if association.Type == 0 || isPolymorphicBelongTo(association.Association) {
+			continue
+		}
+		if next, ok := p[association.Name]; ok {
+			elementType := association.entityType
+			for elementType.Kind() == reflect.Slice || elementType.Kind() == reflect.Pointer {
+				elementType = elementType.Elem()
+			}
+			paths = append(paths, next.eagerPaths(elementType, prefix+association.Name+".")...)
+		} else if association.FetchMode == FetchEagerMode {
+			paths = append(paths, prefix+association.Name)
+		}
+	}
+	return paths
+}
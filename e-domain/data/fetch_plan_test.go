@@ -0,0 +1,89 @@
+package data_test
+
+import (
+	"context"
+	"github.com/reuben-baek/go-learning/e-domain/data"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+	"strings"
+	"testing"
+)
+
+func TestGormRepository_FetchPlan(t *testing.T) {
+	type Company struct {
+		ID   uint
+		Name string
+	}
+	type Category struct {
+		ID   uint
+		Name string
+	}
+	type Product struct {
+		data.LazyLoader `gorm:"-"`
+		ID              uint
+		Name            string
+		CompanyID       uint
+		Company         Company `fetch:"eager"`
+		CategoryID      uint
+		Category        Category
+	}
+
+	db := getGormDB()
+	db.AutoMigrate(&Company{}, &Category{}, &Product{})
+
+	var queries []string
+	db.Callback().Query().After("gorm:query").Register("test:capture_query", func(db *gorm.DB) {
+		queries = append(queries, db.Statement.SQL.String())
+	})
+	countQueries := func(table string) int {
+		count := 0
+		for _, query := range queries {
+			if strings.Contains(query, "FROM `"+table+"`") {
+				count++
+			}
+		}
+		return count
+	}
+
+	transactionManager := data.NewGormTransactionManager(db)
+	productRepository := data.NewGormRepository[Product, uint](transactionManager)
+
+	kakao := Company{Name: "kakao"}
+	db.Create(&kakao)
+	cloud := Category{Name: "cloud"}
+	db.Create(&cloud)
+	db.Create(&Product{Name: "object-storage", CompanyID: kakao.ID, CategoryID: cloud.ID})
+	db.Create(&Product{Name: "block-storage", CompanyID: kakao.ID, CategoryID: cloud.ID})
+
+	ctx := context.Background()
+	err := transactionManager.Do(ctx, func(ctx context.Context) error {
+		queries = nil
+		products, err := productRepository.FindBy(data.WithFetchPlan(ctx, "Category"), "Company", kakao)
+		if err != nil {
+			return err
+		}
+		assert.Equal(t, 2, len(products))
+		assert.Equal(t, 1, countQueries("categories"))
+		assert.Equal(t, 1, countQueries("companies")) // eager by tag, which is kept under the plan
+
+		for i := range products {
+			category, err := data.LazyLoadNow[Category]("Category", &products[i])
+			assert.Nil(t, err)
+			assert.Equal(t, cloud, category)
+			company, err := data.LazyLoadNow[Company]("Company", &products[i])
+			assert.Nil(t, err)
+			assert.Equal(t, kakao, company)
+		}
+		assert.Equal(t, 1, countQueries("categories"))
+		assert.Equal(t, 1, countQueries("companies"))
+		return nil
+	})
+	assert.Nil(t, err)
+
+	t.Run("eager tags without plan", func(t *testing.T) {
+		queries = nil
+		productRepository.FindOne(ctx, 1)
+		assert.Equal(t, 0, countQueries("categories"))
+		assert.Equal(t, 1, countQueries("companies"))
+	})
+}
@@ -0,0 +1,191 @@
+package data
+
+import (
+	"context"
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+	"math"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// ReplicaPolicy selects a read replica.
+type ReplicaPolicy interface {
+	Select(replicas []*gorm.DB) int
+}
+
+// RoundRobinPolicy selects replicas in turn.
+type RoundRobinPolicy struct {
+	next atomic.Uint64
+}
+
+func NewRoundRobinPolicy() *RoundRobinPolicy {
+	return &RoundRobinPolicy{}
+}
+
+func (r *RoundRobinPolicy) Select(replicas []*gorm.DB) int {
+	return int((r.next.Add(1) - 1) % uint64(len(replicas)))
+}
+
+// LeastLatencyPolicy selects the replica with the least moving average of observed query latencies.
+// Replicas without observation are selected first.
+type LeastLatencyPolicy struct {
+	m         sync.Mutex
+	latencies map[*gorm.DB]time.Duration
+}
+
+func NewLeastLatencyPolicy() *LeastLatencyPolicy {
+	return &LeastLatencyPolicy{latencies: make(map[*gorm.DB]time.Duration)}
+}
+
+func (l *LeastLatencyPolicy) Select(replicas []*gorm.DB) int {
+	l.m.Lock()
+	defer l.m.Unlock()
+	selected := 0
+	least := time.Duration(math.MaxInt64)
+	for i, replica := range replicas {
+		latency, ok := l.latencies[replica]
+		if !ok {
+			return i
+		}
+		if latency < least {
+			selected, least = i, latency
+		}
+	}
+	return selected
+}
+
+// Observe records a query latency of replica.
+func (l *LeastLatencyPolicy) Observe(replica *gorm.DB, latency time.Duration) {
+	l.m.Lock()
+	defer l.m.Unlock()
+	if average, ok := l.latencies[replica]; ok {
+		l.latencies[replica] = (average*4 + latency) / 5
+	} else {
+		l.latencies[replica] = latency
+	}
+}
+
+// GormReplicaTransactionManager routes reads to read replicas and writes to the primary of GormTransactionManager.
+// Reads go to the primary in a transaction, or after a write in a context prepared by WithReadYourWrites.
+// Replicas are of the default datasource, and reads of other datasources go to their primaries.
+type GormReplicaTransactionManager struct {
+	*GormTransactionManager
+	replicas []*gorm.DB
+	policy   ReplicaPolicy
+}
+
+func NewGormReplicaTransactionManager(primary *GormTransactionManager, replicas []*gorm.DB, policy ReplicaPolicy) *GormReplicaTransactionManager {
+	if len(replicas) == 0 {
+		panic("NewGormReplicaTransactionManager: no replica")
+	}
+	if observer, ok := policy.(*LeastLatencyPolicy); ok {
+		for _, replica := range replicas {
+			latencyObservers.add(replica, observer)
+		}
+	}
+	return &GormReplicaTransactionManager{
+		GormTransactionManager: primary,
+		replicas:               replicas,
+		policy:                 policy,
+	}
+}
+
+type readYourWritesKey struct{}
+type usePrimaryKey struct{}
+
+// WithReadYourWrites returns a context in which reads go to the primary once a write has been done.
+func WithReadYourWrites(ctx context.Context) context.Context {
+	return context.WithValue(ctx, readYourWritesKey{}, &atomic.Bool{})
+}
+
+// UsePrimary returns a context in which reads go to the primary.
+func UsePrimary(ctx context.Context) context.Context {
+	return context.WithValue(ctx, usePrimaryKey{}, true)
+}
+
+func (r *GormReplicaTransactionManager) Get(ctx context.Context) any {
+	if written, ok := ctx.Value(readYourWritesKey{}).(*atomic.Bool); ok {
+		written.Store(true)
+	}
+	return r.GormTransactionManager.Get(ctx)
+}
+
+func (r *GormReplicaTransactionManager) GetForRead(ctx context.Context) any {
+	return r.GetDataSourceForRead(ctx, DefaultDataSource)
+}
+
+// GetDataSourceForRead returns *gorm.DB of a replica for reads of the default datasource, or of the primary
+// if the datasource is in a transaction of ctx.
+func (r *GormReplicaTransactionManager) GetDataSourceForRead(ctx context.Context, dataSource string) any {
+	if dataSource != DefaultDataSource {
+		return r.GormTransactionManager.GetDataSource(ctx, dataSource)
+	}
+	if _, ok := ctx.Value(gormTransactionKey{dataSource: dataSource}).(*gormTransaction); ok {
+		return r.GormTransactionManager.GetDataSource(ctx, dataSource)
+	}
+	if usePrimary, _ := ctx.Value(usePrimaryKey{}).(bool); usePrimary {
+		return r.GormTransactionManager.GetDataSource(ctx, dataSource)
+	}
+	if written, ok := ctx.Value(readYourWritesKey{}).(*atomic.Bool); ok && written.Load() {
+		logrus.Debugf("GormReplicaTransactionManager.GetForRead: read your writes from primary")
+		return r.GormTransactionManager.GetDataSource(ctx, dataSource)
+	}
+	i := r.policy.Select(r.replicas)
+	logrus.Debugf("GormReplicaTransactionManager.GetForRead: replica [%d]", i)
+	return r.replicas[i].WithContext(ctx)
+}
+
+const latencyStartKey = "data:latency_start"
+
+// latencyObservers are LeastLatencyPolicy observing replicas, by the callbacks of replicas.
+// Callbacks are registered once for a replica, and dispatch latencies to every policy of it.
+var latencyObservers = &latencyObserverRegistry{observers: make(map[*gorm.Config]*replicaObservers)}
+
+type latencyObserverRegistry struct {
+	m         sync.Mutex
+	observers map[*gorm.Config]*replicaObservers
+}
+
+type replicaObservers struct {
+	m         sync.RWMutex
+	replica   *gorm.DB
+	observers []*LeastLatencyPolicy
+}
+
+func (r *latencyObserverRegistry) add(replica *gorm.DB, observer *LeastLatencyPolicy) {
+	r.m.Lock()
+	defer r.m.Unlock()
+	// sessions of a database share the config, which has callbacks
+	observers, ok := r.observers[replica.Config]
+	if !ok {
+		observers = &replicaObservers{replica: replica}
+		r.observers[replica.Config] = observers
+		replica.Callback().Query().Before("gorm:query").Register("data:latency_before_query", func(db *gorm.DB) {
+			db.InstanceSet(latencyStartKey, time.Now())
+		})
+		replica.Callback().Query().After("gorm:query").Register("data:latency_after_query", observers.observe)
+	}
+	observers.m.Lock()
+	defer observers.m.Unlock()
+	for _, v := range observers.observers {
+		if v == observer {
+			return
+		}
+	}
+	observers.observers = append(observers.observers, observer)
+}
+
+func (o *replicaObservers) observe(db *gorm.DB) {
+	start, ok := db.InstanceGet(latencyStartKey)
+	if !ok {
+		return
+	}
+	latency := time.Since(start.(time.Time))
+	o.m.RLock()
+	defer o.m.RUnlock()
+	for _, observer := range o.observers {
+		observer.Observe(o.replica, latency)
+	}
+}
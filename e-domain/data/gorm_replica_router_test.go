@@ -0,0 +1,163 @@
+package data_test
+
+import (
+	"context"
+	"fmt"
+	"github.com/reuben-baek/go-learning/e-domain/data"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestGormReplicaTransactionManager(t *testing.T) {
+	type Company struct {
+		ID   uint
+		Name string
+	}
+
+	dir := t.TempDir()
+	openDB := func(name string) *gorm.DB {
+		db, err := gorm.Open(sqlite.Open(filepath.Join(dir, name+".db")), &gorm.Config{})
+		if err != nil {
+			panic("failed to connect database")
+		}
+		db.AutoMigrate(&Company{})
+		// each database has the same row with its own name to tell where it is read from.
+		db.Create(&Company{ID: 1, Name: name})
+		return db
+	}
+	primary := openDB("primary")
+	replica1 := openDB("replica1")
+	replica2 := openDB("replica2")
+
+	t.Run("round robin", func(t *testing.T) {
+		transactionManager := data.NewGormReplicaTransactionManager(data.NewGormTransactionManager(primary),
+			[]*gorm.DB{replica1, replica2}, data.NewRoundRobinPolicy())
+		companyRepository := data.NewGormRepository[Company, uint](transactionManager)
+
+		ctx := context.Background()
+		var names []string
+		for i := 0; i < 4; i++ {
+			found, err := companyRepository.FindOne(ctx, 1)
+			assert.Nil(t, err)
+			names = append(names, found.Name)
+		}
+		assert.Equal(t, []string{"replica1", "replica2", "replica1", "replica2"}, names)
+	})
+	t.Run("transaction reads from primary", func(t *testing.T) {
+		transactionManager := data.NewGormReplicaTransactionManager(data.NewGormTransactionManager(primary),
+			[]*gorm.DB{replica1, replica2}, data.NewRoundRobinPolicy())
+		companyRepository := data.NewGormRepository[Company, uint](transactionManager)
+
+		var found Company
+		err := transactionManager.Do(context.Background(), func(ctx context.Context) error {
+			var err error
+			found, err = companyRepository.FindOne(ctx, 1)
+			return err
+		})
+		assert.Nil(t, err)
+		assert.Equal(t, "primary", found.Name)
+	})
+	t.Run("read your writes", func(t *testing.T) {
+		transactionManager := data.NewGormReplicaTransactionManager(data.NewGormTransactionManager(primary),
+			[]*gorm.DB{replica1, replica2}, data.NewRoundRobinPolicy())
+		companyRepository := data.NewGormRepository[Company, uint](transactionManager)
+
+		ctx := data.WithReadYourWrites(context.Background())
+		found, _ := companyRepository.FindOne(ctx, 1)
+		assert.Equal(t, "replica1", found.Name)
+
+		created, err := companyRepository.Create(ctx, Company{Name: "kakao"})
+		assert.Nil(t, err)
+
+		found, err = companyRepository.FindOne(ctx, created.ID)
+		assert.Nil(t, err)
+		assert.Equal(t, "kakao", found.Name)
+		found, _ = companyRepository.FindOne(ctx, 1)
+		assert.Equal(t, "primary", found.Name)
+
+		updated, err := companyRepository.Update(context.Background(), Company{ID: created.ID, Name: "kakao enterprise"})
+		assert.Nil(t, err)
+		assert.Equal(t, "kakao enterprise", updated.Name)
+	})
+	t.Run("least latency", func(t *testing.T) {
+		policy := data.NewLeastLatencyPolicy()
+		transactionManager := data.NewGormReplicaTransactionManager(data.NewGormTransactionManager(primary),
+			[]*gorm.DB{replica1, replica2}, policy)
+		companyRepository := data.NewGormRepository[Company, uint](transactionManager)
+
+		ctx := context.Background()
+		policy.Observe(replica1, 10*time.Second)
+		found, _ := companyRepository.FindOne(ctx, 1)
+		assert.Equal(t, "replica2", found.Name) // not observed yet
+		found, _ = companyRepository.FindOne(ctx, 1)
+		assert.Equal(t, "replica2", found.Name) // observed latency is far less than 10s
+
+		policy.Observe(replica2, time.Minute)
+		policy.Observe(replica2, time.Minute)
+		found, _ = companyRepository.FindOne(ctx, 1)
+		assert.Equal(t, "replica1", found.Name)
+	})
+	t.Run("latency observers are registered once", func(t *testing.T) {
+		var warnings []string
+		replica := openDB("replica3")
+		replica.Logger = warningRecorder{Interface: replica.Logger, warnings: &warnings}
+		policy1 := data.NewLeastLatencyPolicy()
+		policy2 := data.NewLeastLatencyPolicy()
+		data.NewGormReplicaTransactionManager(data.NewGormTransactionManager(primary), []*gorm.DB{replica, replica1}, policy1)
+		transactionManager := data.NewGormReplicaTransactionManager(data.NewGormTransactionManager(primary),
+			[]*gorm.DB{replica, replica1}, policy2)
+		assert.Empty(t, warnings)
+
+		companyRepository := data.NewGormRepository[Company, uint](transactionManager)
+		found, _ := companyRepository.FindOne(context.Background(), 1)
+		assert.Equal(t, "replica3", found.Name)
+		// the latency of replica3 is observed by both policies, which select replica1 not observed yet
+		assert.Equal(t, 1, policy1.Select([]*gorm.DB{replica, replica1}))
+		assert.Equal(t, 1, policy2.Select([]*gorm.DB{replica, replica1}))
+	})
+	t.Run("reads of the datasource in a transaction", func(t *testing.T) {
+		orders := openDB("orders")
+		transactionManager := data.NewGormReplicaTransactionManager(
+			data.NewGormTransactionManager(primary, data.WithDataSource("orders", orders)),
+			[]*gorm.DB{replica1}, data.NewRoundRobinPolicy())
+		companyRepository := data.NewGormRepository[Company, uint](transactionManager)
+		defaultRepository := data.NewGormRepositoryOn[Company, uint](transactionManager, data.DefaultDataSource)
+		ordersRepository := data.NewGormRepositoryOn[Company, uint](transactionManager, "orders")
+
+		found, _ := defaultRepository.FindOne(context.Background(), 1)
+		assert.Equal(t, "replica1", found.Name)
+		err := transactionManager.DoOn(context.Background(), []string{"orders"}, func(ctx context.Context) error {
+			found, _ := companyRepository.FindOne(ctx, 1)
+			assert.Equal(t, "replica1", found.Name) // the default datasource is not in the transaction
+			return nil
+		})
+		assert.Nil(t, err)
+		err = transactionManager.DoOn(context.Background(), []string{"orders"}, func(ctx context.Context) error {
+			found, _ := ordersRepository.FindOne(ctx, 1)
+			assert.Equal(t, "orders", found.Name)
+			return nil
+		})
+		assert.Nil(t, err)
+		err = transactionManager.Do(context.Background(), func(ctx context.Context) error {
+			found, _ := defaultRepository.FindOne(ctx, 1)
+			assert.Equal(t, "primary", found.Name)
+			return nil
+		})
+		assert.Nil(t, err)
+	})
+}
+
+// warningRecorder records warnings of GORM, such as duplicated callbacks.
+type warningRecorder struct {
+	logger.Interface
+	warnings *[]string
+}
+
+func (w warningRecorder) Warn(ctx context.Context, msg string, data ...interface{}) {
+	*w.warnings = append(*w.warnings, fmt.Sprintf(msg, data...))
+}
@@ -12,24 +12,85 @@ import (
 
 type GormRepository[T any, ID comparable] struct {
 	transactionManager TransactionManager
+	dataSource         string
 }
 
 func NewGormRepository[T any, ID comparable](transactionManager TransactionManager) *GormRepository[T, ID] {
+	mustValidateMapping[T]()
 	return &GormRepository[T, ID]{transactionManager: transactionManager}
 }
 
+// NewGormRepositoryOn returns GormRepository bound to the named datasource of transactionManager.
+func NewGormRepositoryOn[T any, ID comparable](transactionManager DataSourceTransactionManager, dataSource string) *GormRepository[T, ID] {
+	mustValidateMapping[T]()
+	return &GormRepository[T, ID]{transactionManager: transactionManager, dataSource: dataSource}
+}
+
 func (u *GormRepository[T, ID]) getGormDB(ctx context.Context) *gorm.DB {
-	db, ok := u.transactionManager.Get(ctx).(*gorm.DB)
+	var session any
+	if u.dataSource == "" {
+		session = u.transactionManager.Get(ctx)
+	} else {
+		session = u.transactionManager.(DataSourceTransactionManager).GetDataSource(ctx, u.dataSource)
+	}
+	db, ok := session.(*gorm.DB)
+	if !ok {
+		panic("GormRepository: fail to get *gorm.DB")
+	}
+	return db
+}
+
+// getReadGormDB returns *gorm.DB for read-only queries, which may be routed to a read replica.
+func (u *GormRepository[T, ID]) getReadGormDB(ctx context.Context) *gorm.DB {
+	var session any
+	if u.dataSource == "" {
+		readTransactionManager, ok := u.transactionManager.(ReadTransactionManager)
+		if !ok {
+			return u.getGormDB(ctx)
+		}
+		session = readTransactionManager.GetForRead(ctx)
+	} else {
+		readTransactionManager, ok := u.transactionManager.(ReadDataSourceTransactionManager)
+		if !ok {
+			return u.getGormDB(ctx)
+		}
+		session = readTransactionManager.GetDataSourceForRead(ctx, u.dataSource)
+	}
+	db, ok := session.(*gorm.DB)
 	if !ok {
-		panic("GormRepository.findOne: fail to get *gorm.DB")
+		panic("GormRepository: fail to get *gorm.DB")
 	}
 	return db
 }
 
+func mustValidateMapping[T any]() {
+	if err := validateMapping(reflect.TypeOf((*T)(nil)).Elem()); err != nil {
+		panic(err)
+	}
+}
+
+// associationsOf returns association metadata of the entity type of ptrToEntity.
+func associationsOf(ptrToEntity any) []associationMetadata {
+	entityType := reflect.TypeOf(ptrToEntity).Elem()
+	if entityType.Kind() != reflect.Struct {
+		return nil
+	}
+	return metadataOf(entityType).associations
+}
+
 // findOne returns entity
 func (u *GormRepository[T, ID]) findOne(ctx context.Context, ptrToEntity any, id any) (any, error) {
-	db := u.getGormDB(ctx)
-	db = u.preload(db, ptrToEntity)
+	if _, planned := fetchPlanFrom(ctx, reflect.TypeOf(ptrToEntity).Elem()); !planned {
+		if identities, ok := identityMapFrom(ctx); ok {
+			if key, ok := identityKey(reflect.TypeOf(ptrToEntity).Elem(), id); ok && identities.get(key, ptrToEntity) {
+				logrus.Debugf("GormRepository.findOne: found %s[%v] in identity map", key.entityType, key.id)
+				u.loaded(ctx, ptrToEntity, id)
+				return reflect.Indirect(reflect.ValueOf(ptrToEntity)).Interface(), nil
+			}
+		}
+	}
+	db := u.getReadGormDB(ctx)
+	db = u.preload(ctx, db, ptrToEntity)
 	if err := db.First(ptrToEntity, "id = ?", id).Error; err != nil {
 		if logrus.IsLevelEnabled(logrus.DebugLevel) {
 			var idd any
@@ -46,16 +107,16 @@ func (u *GormRepository[T, ID]) findOne(ctx context.Context, ptrToEntity any, id
 		}
 	}
 
-	u.setLazyLoader(ctx, ptrToEntity, id)
+	u.loaded(ctx, ptrToEntity, id)
 
 	return reflect.Indirect(reflect.ValueOf(ptrToEntity)).Interface(), nil
 }
 
-// findOneByForeignKey returns ptrEoEntity
-func (u *GormRepository[T, ID]) findOneByForeignKey(ctx context.Context, ptrToEntity any, foreignKey string, id any) (any, error) {
-	db := u.getGormDB(ctx)
-	db = u.preload(db, ptrToEntity)
-	if err := db.Model(ptrToEntity).First(ptrToEntity, fmt.Sprintf("%s = ?", foreignKey), id).Error; err != nil {
+// findOneByForeignKey returns ptrEoEntity. conditions are the foreign key, and the polymorphic type if any.
+func (u *GormRepository[T, ID]) findOneByForeignKey(ctx context.Context, ptrToEntity any, conditions map[string]any, id any) (any, error) {
+	db := u.getReadGormDB(ctx)
+	db = u.preload(ctx, db, ptrToEntity)
+	if err := db.Model(ptrToEntity).First(ptrToEntity, conditions).Error; err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
 			return nil, NotFoundError
 		} else {
@@ -63,16 +124,16 @@ func (u *GormRepository[T, ID]) findOneByForeignKey(ctx context.Context, ptrToEn
 		}
 	}
 
-	u.setLazyLoader(ctx, ptrToEntity, id)
+	u.loaded(ctx, ptrToEntity, id)
 	return reflect.Indirect(reflect.ValueOf(ptrToEntity)).Interface(), nil
 }
 
-func (u *GormRepository[T, ID]) findByForeignKey(ctx context.Context, ptrToSlice any, foreignKey string, id any) (any, error) {
-	db := u.getGormDB(ctx)
+func (u *GormRepository[T, ID]) findByForeignKey(ctx context.Context, ptrToSlice any, conditions map[string]any, id any) (any, error) {
+	db := u.getReadGormDB(ctx)
 	ptrToElement := ptrToEmptyElementOfPtrToSlice(ptrToSlice)
-	db = u.preload(db, ptrToElement)
+	db = u.preload(ctx, db, ptrToElement)
 
-	if err := db.Model(ptrToSlice).Find(ptrToSlice, fmt.Sprintf("%s = ?", foreignKey), id).Error; err != nil {
+	if err := db.Model(ptrToSlice).Find(ptrToSlice, conditions).Error; err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
 			return nil, NotFoundError
 		} else {
@@ -83,53 +144,52 @@ func (u *GormRepository[T, ID]) findByForeignKey(ctx context.Context, ptrToSlice
 	// for each element, set lazy loader
 	elementValues := reflect.ValueOf(ptrToSlice).Elem()
 	for i := 0; i < elementValues.Len(); i++ {
-		u.setLazyLoader(ctx, elementValues.Index(i).Addr().Interface(), id)
+		u.loaded(ctx, elementValues.Index(i).Addr().Interface(), id)
 	}
+	u.setBatchLoadFuncs(ctx, elementValues)
 
 	return reflect.Indirect(reflect.ValueOf(ptrToSlice)).Interface(), nil
 }
 
-func (u *GormRepository[T, ID]) findWithChildTable(ctx context.Context, ptrToSlice any, associationName string, foreignKey string, foreignKeyValue any) (any, error) {
-	db := u.getGormDB(ctx)
-	ptrToElement := ptrToEmptyElementOfPtrToSlice(ptrToSlice)
-	joinQuery, whereQuery := buildQueryForFindWithChildTable(ptrToElement, associationName, foreignKey)
-
+func (u *GormRepository[T, ID]) findWithChildTable(ctx context.Context, ptrToSlice any, mapping associationMapping, foreignKeyValue any) (any, error) {
+	if mapping.associationPrimaryKey == "" {
+		return nil, fmt.Errorf("%s has no primary key to find by", mapping.associationTable)
+	}
+	joinQuery, whereQuery := buildQueryForFindWithChildTable(mapping)
 	// select * from users left join credit_cards on users.id = credit_cards.user_id where credit_cards.id = 1
-	if err := db.Model(ptrToSlice).Joins(joinQuery).Where(whereQuery, foreignKeyValue).Find(ptrToSlice).Error; err != nil {
-		if errors.Is(err, gorm.ErrRecordNotFound) {
-			return nil, NotFoundError
-		} else {
-			return nil, err
-		}
+	if mapping.polymorphicType != "" {
+		// ... and comments.owner_type = 'users'
+		return u.findWithJoin(ctx, ptrToSlice, joinQuery, whereQuery, foreignKeyValue, mapping.polymorphicValue)
 	}
+	return u.findWithJoin(ctx, ptrToSlice, joinQuery, whereQuery, foreignKeyValue)
+}
 
-	// for each element, set lazy loader
-	elementValues := reflect.ValueOf(ptrToSlice).Elem()
-	for i := 0; i < elementValues.Len(); i++ {
-		value := elementValues.Index(i)
-		id := value.FieldByName("ID").Interface()
-		u.setLazyLoader(ctx, value.Addr().Interface(), id)
+func buildQueryForFindWithChildTable(mapping associationMapping) (string, string) {
+	joinQuery := fmt.Sprintf("left join %s on %s.%s = %s.%s", mapping.associationTable, mapping.table, mapping.key, mapping.associationTable, mapping.associationKey)
+	whereQuery := fmt.Sprintf("%s.%s = ?", mapping.associationTable, mapping.associationPrimaryKey)
+	if mapping.polymorphicType != "" {
+		whereQuery += fmt.Sprintf(" and %s.%s = ?", mapping.associationTable, mapping.polymorphicType)
 	}
+	return joinQuery, whereQuery
+}
 
-	return reflect.Indirect(reflect.ValueOf(ptrToSlice)).Interface(), nil
+func (u *GormRepository[T, ID]) findWithJoinTable(ctx context.Context, ptrToSlice any, mapping associationMapping, foreignKeyValue any) (any, error) {
+	joinQuery, whereQuery := buildQueryForFindWithJoinTable(mapping)
+	// select * from users left join user_languages on users.id = user_languages.user_id where user_languages.language_id = 1
+	return u.findWithJoin(ctx, ptrToSlice, joinQuery, whereQuery, foreignKeyValue)
 }
 
-func buildQueryForFindWithChildTable(ptrToElement any, associationName string, foreignKey string) (string, string) {
-	elementType := reflect.TypeOf(ptrToElement).Elem().Name()
-	elementTable := fmt.Sprintf("%ss", toSnakeCase(elementType))
-	childTable := toSnakeCase(associationName)
-	joinQuery := fmt.Sprintf("left join %s on %s.id = %s.%s", childTable, elementTable, childTable, foreignKey)
-	whereQuery := fmt.Sprintf("%s.id = ?", childTable)
+func buildQueryForFindWithJoinTable(mapping associationMapping) (string, string) {
+	joinQuery := fmt.Sprintf("left join %s on %s.%s = %s.%s", mapping.joinTable, mapping.table, mapping.key, mapping.joinTable, mapping.joinKey)
+	whereQuery := fmt.Sprintf("%s.%s = ?", mapping.joinTable, mapping.joinAssociationKey)
 	return joinQuery, whereQuery
 }
 
-func (u *GormRepository[T, ID]) findWithJoinTable(ctx context.Context, ptrToSlice any, associationName string, foreignKey string, foreignKeyValue any) (any, error) {
-	db := u.getGormDB(ctx)
+func (u *GormRepository[T, ID]) findWithJoin(ctx context.Context, ptrToSlice any, joinQuery string, whereQuery string, values ...any) (any, error) {
+	db := u.getReadGormDB(ctx)
 	ptrToElement := ptrToEmptyElementOfPtrToSlice(ptrToSlice)
-	joinQuery, whereQuery := buildQueryForFindWithJoinTable(ptrToElement, associationName, foreignKey)
-
-	// select * from users left join user_languages on users.id = user_languages.user_id where user_languages.language_id = 1
-	if err := db.Model(ptrToSlice).Joins(joinQuery).Where(whereQuery, foreignKeyValue).Find(ptrToSlice).Error; err != nil {
+	db = u.preload(ctx, db, ptrToElement)
+	if err := db.Model(ptrToSlice).Joins(joinQuery).Where(whereQuery, values...).Find(ptrToSlice).Error; err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
 			return nil, NotFoundError
 		} else {
@@ -141,25 +201,16 @@ func (u *GormRepository[T, ID]) findWithJoinTable(ctx context.Context, ptrToSlic
 	elementValues := reflect.ValueOf(ptrToSlice).Elem()
 	for i := 0; i < elementValues.Len(); i++ {
 		value := elementValues.Index(i)
-		id := value.FieldByName("ID").Interface()
-		u.setLazyLoader(ctx, value.Addr().Interface(), id)
+		id := idValueOf(value.Addr().Interface())
+		u.loaded(ctx, value.Addr().Interface(), id)
 	}
+	u.setBatchLoadFuncs(ctx, elementValues)
 
 	return reflect.Indirect(reflect.ValueOf(ptrToSlice)).Interface(), nil
 }
 
-func buildQueryForFindWithJoinTable(ptrToElement any, associationName string, foreignKey string) (string, string) {
-	elementType := reflect.TypeOf(ptrToElement).Elem().Name()
-	elementTable := toSnakeCase(elementType + "s")
-	joinTable := fmt.Sprintf("%s_%s", toSnakeCase(elementType), toSnakeCase(associationName))
-	elementForeignKey := fmt.Sprintf("%s_id", toSnakeCase(elementType))
-	joinQuery := fmt.Sprintf("left join %s on %s.id = %s.%s", joinTable, elementTable, joinTable, elementForeignKey)
-	whereQuery := fmt.Sprintf("%s.%s = ?", joinTable, foreignKey)
-	return joinQuery, whereQuery
-}
-
 func (u *GormRepository[T, ID]) findAssociationsByForeignKey(ctx context.Context, ptrToParent any, ptrToChildren any, associationName string, foreignKey string, foreignKeyValue any) (any, error) {
-	db := u.getGormDB(ctx)
+	db := u.getReadGormDB(ctx)
 	association := db.Model(ptrToParent).Association(associationName)
 
 	if err := association.Find(ptrToChildren, fmt.Sprintf("%s = ?", foreignKey), foreignKeyValue); err != nil {
@@ -173,34 +224,107 @@ func (u *GormRepository[T, ID]) findAssociationsByForeignKey(ctx context.Context
 	// for each element, set lazy loader
 	elementValues := reflect.ValueOf(ptrToChildren).Elem()
 	for i := 0; i < elementValues.Len(); i++ {
-		u.setLazyLoader(ctx, elementValues.Index(i).Addr().Interface(), foreignKeyValue)
+		u.loaded(ctx, elementValues.Index(i).Addr().Interface(), foreignKeyValue)
 	}
+	u.setBatchLoadFuncs(ctx, elementValues)
 
 	return reflect.Indirect(reflect.ValueOf(ptrToChildren)).Interface(), nil
 }
 
+// loaded sets lazy loaders of an entity loaded from database, caches it in the identity map of the transaction
+// and tracks it in UnitOfWork of ctx, if any.
+func (u *GormRepository[T, ID]) loaded(ctx context.Context, ptrToEntity any, id any) any {
+	if identities, ok := identityMapFrom(ctx); ok {
+		if key, ok := identityKey(reflect.TypeOf(ptrToEntity).Elem(), idValueOf(ptrToEntity)); ok {
+			identities.put(key, ptrToEntity)
+		}
+	}
+	if uow, ok := unitOfWorkFrom(ctx); ok && idValueOf(ptrToEntity) != nil {
+		key := entityKey{entityType: reflect.TypeOf(ptrToEntity).Elem(), id: findIDValue(ptrToEntity, "ID")}
+		uow.snapshot(key, takeSnapshot(u.getReadGormDB(ctx), ptrToEntity))
+	}
+	return u.setLazyLoader(ctx, ptrToEntity, id)
+}
+
+// evict removes entity from the identity map of the transaction.
+func (u *GormRepository[T, ID]) evict(ctx context.Context, entity T) {
+	if identities, ok := identityMapFrom(ctx); ok {
+		ptrToEntity := ptrToConcrete(&entity)
+		if key, ok := identityKey(reflect.TypeOf(ptrToEntity).Elem(), findIDValue(ptrToEntity, "ID")); ok {
+			identities.remove(key)
+		}
+	}
+}
+
 func (u *GormRepository[T, ID]) setLazyLoader(ctx context.Context, ptrToEntity any, id any) any {
+	var plan fetchPlan
+	if bound, ok := fetchPlanFrom(ctx, reflect.TypeOf(ptrToEntity).Elem()); ok {
+		plan = bound.plan
+	}
+	return u.setLazyLoaderByPlan(withoutFetchPlan(ctx), ptrToEntity, id, plan)
+}
+
+// setLazyLoaderByPlan sets lazy loaders of associations not fetched by plan, also in associations fetched by plan.
+// Associations fetched by plan get load functions returning the fetched value, to be accessed like lazy ones.
+func (u *GormRepository[T, ID]) setLazyLoaderByPlan(ctx context.Context, ptrToEntity any, id any, plan fetchPlan) any {
 	associations := findAssociations(ptrToEntity)
 
 	switch anyEntity := ptrToEntity.(type) {
 	case LazyLoadable:
 		anyEntity.NewInstance()
 		for _, v := range associations {
-			switch v.FetchMode {
-			case FetchLazyMode:
+			if isPolymorphicBelongTo(v) {
+				anyEntity.SetLoadFunc(v.Name, u.getLazyLoadFuncOfPolymorphic(ctx, ptrToEntity, v))
+				continue
+			}
+			switch plan.fetchMode(v) {
+			case FetchEagerMode:
+				if plan == nil {
+					continue
+				}
+				field := reflect.ValueOf(ptrToEntity).Elem().FieldByName(v.Name)
+				u.setFetchedLazyLoaders(ctx, field, plan[v.Name])
+				fetched := field.Interface()
+				if field.Kind() == reflect.Pointer {
+					if field.IsNil() {
+						fetched = nil
+					} else {
+						fetched = field.Elem().Interface()
+					}
+				}
+				anyEntity.SetLoadFunc(v.Name, func() (any, error) {
+					return fetched, nil
+				})
+			case FetchLazyMode, FetchBatchMode:
+				mapping, err := resolveAssociation(u.getReadGormDB(ctx), ptrToEntity, v)
+				if err != nil {
+					logrus.Errorf("GormRepository.setLazyLoader: fail to resolve %s of %T - %v", v.Name, ptrToEntity, err)
+					anyEntity.SetLoadFunc(v.Name, func() (any, error) {
+						return nil, err
+					})
+					continue
+				}
 				switch v.Type {
 				case BelongTo:
 					logrus.Debugf("GormRepository.FindOne: SetLoadFunc belong-to entity [%p], association [%p], association_id [%v]", anyEntity, v.PtrToEntity, v.ID)
-					anyEntity.SetLoadFunc(v.Name, u.GetLazyLoadFuncOfBelongTo(ctx, v.PtrToEntity, v.ID))
+					if v.References == "" {
+						anyEntity.SetLoadFunc(v.Name, u.GetLazyLoadFuncOfBelongTo(ctx, v.PtrToEntity, v.ID))
+					} else {
+						anyEntity.SetLoadFunc(v.Name, u.getLazyLoadFuncOfBelongToBy(ctx, v.PtrToEntity, mapping.associationKey, v.ID))
+					}
 				case HasOne:
-					logrus.Debugf("GormRepository.FindOne: SetLoadFunc has-one entity [%p], association [%p], foreignKey [%s], foreignKeyValue [%v]", anyEntity, v.PtrToEntity, v.ForeignKey, id)
-					anyEntity.SetLoadFunc(v.Name, u.GetLazyLoadFuncOfHasOne(ctx, v.PtrToEntity, v.ForeignKey, id))
+					foreignKeyValue := findIDValue(ptrToEntity, mapping.keyField)
+					logrus.Debugf("GormRepository.FindOne: SetLoadFunc has-one entity [%p], association [%p], foreignKey [%s], foreignKeyValue [%v]", anyEntity, v.PtrToEntity, mapping.associationKey, foreignKeyValue)
+					anyEntity.SetLoadFunc(v.Name, u.getLazyLoadFuncOfHasOneBy(ctx, v.PtrToEntity, mapping.whereForeignKey(mapping.associationKey, foreignKeyValue), foreignKeyValue))
 				case HasMany:
-					logrus.Debugf("GormRepository.FindOne: SetLoadFunc has-many entity [%p], association [%p], foreignKey [%s], foreignKeyValue [%v]", anyEntity, v.PtrToEntity, v.ForeignKey, id)
-					anyEntity.SetLoadFunc(v.Name, u.GetLazyLoadFuncOfHasMany(ctx, v.PtrToEntity, v.ForeignKey, id))
+					foreignKeyValue := findIDValue(ptrToEntity, mapping.keyField)
+					logrus.Debugf("GormRepository.FindOne: SetLoadFunc has-many entity [%p], association [%p], foreignKey [%s], foreignKeyValue [%v]", anyEntity, v.PtrToEntity, mapping.associationKey, foreignKeyValue)
+					anyEntity.SetLoadFunc(v.Name, u.getLazyLoadFuncOfHasManyBy(ctx, v.PtrToEntity, mapping.whereForeignKey(mapping.associationKey, foreignKeyValue), foreignKeyValue))
 				case ManyToMany:
-					logrus.Debugf("GormRepository.FindOne: SetLoadFunc many-to-many entity [%p], association [%p], foreignKey [%s], foreignKeyValue [%v]", anyEntity, v.PtrToEntity, v.ForeignKey, id)
-					anyEntity.SetLoadFunc(v.Name, u.GetLazyLoadFuncOfManyMany(ctx, ptrToEntity, v.PtrToEntity, v.Name, v.ForeignKey, id))
+					foreignKey := fmt.Sprintf("%s.%s", mapping.joinTable, mapping.joinKey)
+					foreignKeyValue := findIDValue(ptrToEntity, mapping.keyField)
+					logrus.Debugf("GormRepository.FindOne: SetLoadFunc many-to-many entity [%p], association [%p], foreignKey [%s], foreignKeyValue [%v]", anyEntity, v.PtrToEntity, foreignKey, foreignKeyValue)
+					anyEntity.SetLoadFunc(v.Name, u.GetLazyLoadFuncOfManyMany(ctx, ptrToEntity, v.PtrToEntity, v.Name, foreignKey, foreignKeyValue))
 				}
 			}
 		}
@@ -208,12 +332,39 @@ func (u *GormRepository[T, ID]) setLazyLoader(ctx context.Context, ptrToEntity a
 	return ptrToEntity
 }
 
-func (u *GormRepository[T, ID]) preload(db *gorm.DB, ptrToEntity any) *gorm.DB {
-	associations := findAssociations(ptrToEntity)
+// setFetchedLazyLoaders sets lazy loaders of entities in value fetched by plan.
+func (u *GormRepository[T, ID]) setFetchedLazyLoaders(ctx context.Context, value reflect.Value, plan fetchPlan) {
+	switch value.Kind() {
+	case reflect.Pointer:
+		if !value.IsNil() {
+			u.setFetchedLazyLoaders(ctx, value.Elem(), plan)
+		}
+	case reflect.Slice:
+		for i := 0; i < value.Len(); i++ {
+			ptrToElement := value.Index(i).Addr().Interface()
+			u.setLazyLoaderByPlan(ctx, ptrToElement, idValueOf(ptrToElement), plan)
+		}
+		u.setBatchLoadFuncsByPlan(ctx, value, plan)
+	case reflect.Struct:
+		ptrToEntity := value.Addr().Interface()
+		u.setLazyLoaderByPlan(ctx, ptrToEntity, idValueOf(ptrToEntity), plan)
+	}
+}
 
+func (u *GormRepository[T, ID]) preload(ctx context.Context, db *gorm.DB, ptrToEntity any) *gorm.DB {
 	tx := db.Model(ptrToEntity)
-	for _, v := range associations {
-		if v.FetchMode == FetchEagerMode {
+	if bound, ok := fetchPlanFrom(ctx, reflect.TypeOf(ptrToEntity).Elem()); ok {
+		for _, path := range bound.paths {
+			tx = tx.Preload(path)
+		}
+		for _, path := range bound.plan.eagerPaths(reflect.TypeOf(ptrToEntity).Elem(), "") {
+			tx = tx.Preload(path)
+		}
+		return tx
+	}
+
+	for _, v := range metadataOf(reflect.TypeOf(ptrToEntity).Elem()).associations {
+		if v.FetchMode == FetchEagerMode && !isPolymorphicBelongTo(v.Association) {
 			tx = tx.Preload(v.Name)
 		}
 	}
@@ -222,6 +373,10 @@ func (u *GormRepository[T, ID]) preload(db *gorm.DB, ptrToEntity any) *gorm.DB {
 
 func (u *GormRepository[T, ID]) FindOne(ctx context.Context, id ID) (T, error) {
 	var entity T
+	if h, ok := inheritanceOfBase(reflect.TypeOf(&entity).Elem()); ok {
+		return u.findOneOfInheritance(ctx, h, id)
+	}
+	ctx = bindFetchPlan(ctx, reflect.TypeOf(entity))
 	if found, err := u.findOne(ctx, &entity, id); err != nil {
 		return entity, err
 	} else {
@@ -232,62 +387,219 @@ func (u *GormRepository[T, ID]) FindOne(ctx context.Context, id ID) (T, error) {
 func (u *GormRepository[T, ID]) FindBy(ctx context.Context, name string, byEntity any) ([]T, error) {
 	var entity T
 	var entities []T
+	if _, zero := findID[any, any](byEntity); zero {
+		panic(fmt.Sprintf("FindBy: %s's ID field is empty", name))
+	}
+	if h, ok := inheritanceOfBase(reflect.TypeOf(&entity).Elem()); ok {
+		return findAllOfInheritance[T](ctx, h, func(ctx context.Context, ptrToEntity any, ptrToSlice any) (any, error) {
+			return u.findBy(ctx, ptrToEntity, ptrToSlice, name, byEntity)
+		})
+	}
+	ctx = bindFetchPlan(ctx, reflect.TypeOf(entity))
+	found, err := u.findBy(ctx, &entity, &entities, name, byEntity)
+	if err != nil {
+		return entities, err
+	}
+	return found.([]T), nil
+}
 
+// findBy finds entities of ptrToEntity type into ptrToSlice by the association named name, or name+"s".
+// name may be a dotted path of associations, such as "Departments.Company", which joins tables of every hop.
+func (u *GormRepository[T, ID]) findBy(ctx context.Context, ptrToEntity any, ptrToSlice any, name string, byEntity any) (any, error) {
+	if isAssociationPath(name) {
+		return u.findByPath(ctx, ptrToEntity, ptrToSlice, name, byEntity)
+	}
 	byEntityName := name
 	byAssName := byEntityName + "s"
-	associations := findAssociations(entity)
-	foreignKeyValue, zero := findID[any, any](byEntity)
-	if zero {
-		panic(fmt.Sprintf("FindBy: %s's ID field is empty", byEntityName))
-	}
+	associations := findAssociations(ptrToEntity)
+	foreignKeyValue, _ := findID[any, any](byEntity)
 
 	for _, ass := range associations {
 		if ass.Name == byEntityName || ass.Name == byAssName {
+			if isPolymorphicBelongTo(ass) {
+				// owner type is the type of byEntity
+				ass.PtrToEntity = reflect.New(reflect.Indirect(reflect.ValueOf(byEntity)).Type()).Interface()
+			}
+			mapping, err := resolveAssociation(u.getReadGormDB(ctx), ptrToEntity, ass)
+			if err != nil {
+				return nil, err
+			}
+			if ass.Type != HasOne && ass.Type != HasMany {
+				foreignKeyValue = findIDValue(byEntity, mapping.associationKeyField)
+			}
 			switch ass.Type {
 			case BelongTo:
-				foreignKey := fmt.Sprintf("%s_id", toSnakeCase(byEntityName))
-				if found, err := u.findByForeignKey(ctx, &entities, foreignKey, foreignKeyValue); err != nil {
-					return entities, err
-				} else {
-					return found.([]T), nil
-				}
+				return u.findByForeignKey(ctx, ptrToSlice, mapping.whereForeignKey(mapping.key, foreignKeyValue), foreignKeyValue)
 			case HasOne, HasMany:
-				if found, err := u.findWithChildTable(ctx, &entities, byAssName, ass.ForeignKey, foreignKeyValue); err != nil {
-					return entities, err
-				} else {
-					return found.([]T), nil
-				}
+				return u.findWithChildTable(ctx, ptrToSlice, mapping, foreignKeyValue)
 			case ManyToMany:
-				foreignKey := fmt.Sprintf("%s_id", toSnakeCase(byEntityName))
-				if found, err := u.findWithJoinTable(ctx, &entities, ass.Name, foreignKey, foreignKeyValue); err != nil {
-					return entities, err
-				} else {
-					return found.([]T), nil
-				}
+				return u.findWithJoinTable(ctx, ptrToSlice, mapping, foreignKeyValue)
 			}
 		}
 	}
-	return nil, fmt.Errorf("%T has no association with %T", entity, byEntity)
+	return nil, fmt.Errorf("%s has no association with %T", reflect.TypeOf(ptrToEntity).Elem(), byEntity)
 }
 
+// findByPath finds entities by joins of path, selecting distinct entities if a hop of path is to-many.
+func (u *GormRepository[T, ID]) findByPath(ctx context.Context, ptrToEntity any, ptrToSlice any, path string, byEntity any) (any, error) {
+	db := u.getReadGormDB(ctx)
+	p, err := resolveAssociationPath(db, ptrToEntity, path, byEntity)
+	if err != nil {
+		return nil, err
+	}
+	db = u.preload(ctx, db, ptrToEntity)
+	for _, join := range p.joins {
+		db = db.Joins(join.query, join.values...)
+	}
+	if p.toMany {
+		// select distinct employees.* ...
+		db = db.Distinct(p.table + ".*")
+	}
+	if err := db.Model(ptrToSlice).Where(p.where, p.value).Find(ptrToSlice).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, NotFoundError
+		} else {
+			return nil, err
+		}
+	}
+
+	// for each element, set lazy loader
+	elementValues := reflect.ValueOf(ptrToSlice).Elem()
+	for i := 0; i < elementValues.Len(); i++ {
+		value := elementValues.Index(i)
+		u.loaded(ctx, value.Addr().Interface(), idValueOf(value.Addr().Interface()))
+	}
+	u.setBatchLoadFuncs(ctx, elementValues)
+
+	return reflect.Indirect(reflect.ValueOf(ptrToSlice)).Interface(), nil
+}
+
+// FindByValue finds entities whose value object field named name equals value. Every column of the value object is
+// compared, which are the prefixed columns of an embedded one, or the serialized column.
+// Attributes of converters are value objects of a column, and value is converted as they are written.
+func (u *GormRepository[T, ID]) FindByValue(ctx context.Context, name string, value any) ([]T, error) {
+	var entity T
+	var entities []T
+	if h, ok := inheritanceOfBase(reflect.TypeOf(&entity).Elem()); ok {
+		return findAllOfInheritance[T](ctx, h, func(ctx context.Context, ptrToEntity any, ptrToSlice any) (any, error) {
+			return u.findByValue(ctx, ptrToEntity, ptrToSlice, name, value)
+		})
+	}
+	ctx = bindFetchPlan(ctx, reflect.TypeOf(entity))
+	found, err := u.findByValue(ctx, &entity, &entities, name, value)
+	if err != nil {
+		return entities, err
+	}
+	return found.([]T), nil
+}
+
+func (u *GormRepository[T, ID]) findByValue(ctx context.Context, ptrToEntity any, ptrToSlice any, name string, value any) (any, error) {
+	conditions, err := valueObjectConditions(u.getReadGormDB(ctx), ptrToEntity, name, value)
+	if err != nil {
+		return nil, err
+	}
+	return u.findByForeignKey(ctx, ptrToSlice, conditions, nil)
+}
+
+// Create creates entity. In UnitOfWork, entities of assigned IDs are inserted at flush, and entities without ID are
+// inserted now to return IDs generated by database, after pending inserts are flushed for their parents.
+// Entities inserted now are tracked as loaded ones, so later updates of them write only changed columns.
 func (u *GormRepository[T, ID]) Create(ctx context.Context, entity T) (T, error) {
+	if uow, ok := unitOfWorkFrom(ctx); ok {
+		if isNewEntity(ptrToConcrete(&entity)) {
+			if err := uow.flushInserts(ctx); err != nil {
+				var zero T
+				return zero, err
+			}
+			return u.create(ctx, entity)
+		}
+		_ = uow.register(&unitOfWorkEntry{
+			state: pendingInsert,
+			depth: belongToDepth(reflect.TypeOf(ptrToConcrete(&entity)).Elem(), map[reflect.Type]bool{}),
+			flush: func(ctx context.Context) error {
+				_, err := u.create(ctx, entity)
+				return err
+			},
+		})
+		return entity, nil
+	}
+	return u.create(ctx, entity)
+}
+
+// create creates entity with new entities of its aggregate graph by createGraph. Created entities are loaded ones,
+// with lazy loaders set.
+func (u *GormRepository[T, ID]) create(ctx context.Context, entity T) (T, error) {
 	db := u.getGormDB(ctx)
 	var created T
-	if err := db.Create(&entity).Error; err != nil {
+	// the subtype of interface entities
+	ptrToEntity := ptrToConcrete(&entity)
+	nodes, err := createGraph(gormGraphStore{db: db}, ptrToEntity)
+	if err != nil {
 		return created, err
 	}
 
 	var id any
 	var zero bool
-	if id, zero = findID[T, ID](entity); zero {
+	if id, zero = findID[any, ID](ptrToEntity); zero {
 		panic("entity.ID is missing")
 	}
-	created = entity
-	u.setLazyLoader(ctx, &created, id)
-	return created, nil
+	for _, node := range nodes {
+		if node != ptrToEntity {
+			u.loaded(ctx, node, idValueOf(node))
+		}
+	}
+	u.loaded(ctx, ptrToEntity, id)
+	return fromConcrete[T](ptrToEntity), nil
 }
 
 func (u *GormRepository[T, ID]) Update(ctx context.Context, entity T) (T, error) {
+	u.evict(ctx, entity)
+	if uow, ok := unitOfWorkFrom(ctx); ok {
+		id, zero := findID[T, ID](entity)
+		if zero {
+			panic("entity.ID is missing")
+		}
+		key := entityKey{entityType: reflect.TypeOf(ptrToConcrete(&entity)).Elem(), id: id}
+		err := uow.register(&unitOfWorkEntry{
+			key:   key,
+			state: pendingUpdate,
+			flush: func(ctx context.Context) error {
+				return u.flushUpdate(ctx, uow, key, entity)
+			},
+		})
+		return entity, err
+	}
+	return u.update(ctx, entity)
+}
+
+// flushUpdate updates only changed columns of entity, or all columns and associations by update if it has no snapshot
+// or its associations changed from the snapshot.
+func (u *GormRepository[T, ID]) flushUpdate(ctx context.Context, uow *UnitOfWork, key entityKey, entity T) error {
+	db := u.getGormDB(ctx)
+	ptrToEntity := ptrToConcrete(&entity)
+	current := takeSnapshot(db, ptrToEntity)
+	snapshot, ok := uow.getSnapshot(key)
+	if !ok || !reflect.DeepEqual(snapshot.associations, current.associations) {
+		updated, err := u.update(ctx, entity)
+		if err != nil {
+			return err
+		}
+		uow.setSnapshot(key, takeSnapshot(db, ptrToConcrete(&updated)))
+		return nil
+	}
+	changed := changedColumns(snapshot.columns, current.columns)
+	if len(changed) == 0 {
+		return nil
+	}
+	logrus.Debugf("GormRepository.flushUpdate: %s[%v] changed columns %v", key.entityType, key.id, changed)
+	if err := db.Model(ptrToEntity).Updates(changed).Error; err != nil {
+		return err
+	}
+	uow.setSnapshot(key, current)
+	return nil
+}
+
+func (u *GormRepository[T, ID]) update(ctx context.Context, entity T) (T, error) {
 	db := u.getGormDB(ctx)
 	var id any
 	var zero bool
@@ -296,20 +608,32 @@ func (u *GormRepository[T, ID]) Update(ctx context.Context, entity T) (T, error)
 		panic("entity.ID is missing")
 	}
 
-	updateTx := db.Model(&entity).Select("*").Omit("id")
-	update := entity
-	associations := findAssociations(&entity)
-
-	lazyLoader, _ := any(&entity).(LazyLoadable)
+	ptrToEntity := ptrToConcrete(&entity)
+	entityType := reflect.TypeOf(ptrToEntity).Elem()
+	updateTx := db.Model(ptrToEntity).Select("*").Omit("id")
+	update := reflect.New(entityType)
+	update.Elem().Set(reflect.ValueOf(ptrToEntity).Elem())
+	lazyLoader, _ := ptrToEntity.(LazyLoadable)
 
-	for _, association := range associations {
+	for _, association := range associationsOf(ptrToEntity) {
 		updateTx = updateTx.Omit(association.Name)
+		if !association.cascade.has(CascadeMerge) {
+			continue
+		}
 
 		switch association.Type {
 		case BelongTo:
 		case HasOne, HasMany, ManyToMany:
-			associationValue := reflect.ValueOf(entity).FieldByName(association.Name)
-			ass := db.Unscoped().Model(&entity).Association(association.Name)
+			associationValue := reflect.ValueOf(ptrToEntity).Elem().FieldByName(association.Name)
+			if association.JoinEntity {
+				if lazyLoader == nil || !lazyLoader.HasLoadFunc(association.Name) || !associationValue.IsZero() {
+					if err := u.mergeJoinEntities(ctx, db, ptrToEntity, association.Association, associationValue); err != nil {
+						return entity, err
+					}
+				}
+				continue
+			}
+			ass := db.Unscoped().Model(ptrToEntity).Association(association.Name)
 			if ass.Error != nil {
 				panic(ass.Error)
 			}
@@ -327,31 +651,36 @@ func (u *GormRepository[T, ID]) Update(ctx context.Context, entity T) (T, error)
 		}
 	}
 
-	if err := updateTx.Updates(&update).Error; err != nil {
+	if err := updateTx.Updates(update.Interface()).Error; err != nil {
 		return entity, err
 	}
 
 	var updated T
-	if found, err := u.findOne(ctx, &updated, id); err != nil {
+	ptrToUpdated := reflect.New(entityType).Interface()
+	if _, err := u.findOne(UsePrimary(ctx), ptrToUpdated, id); err != nil {
 		return updated, err
-	} else {
-		return found.(T), err
 	}
+	return fromConcrete[T](ptrToUpdated), nil
 }
 
-func (u *GormRepository[T, ID]) replaceAssociation(associationValue reflect.Value, ass *gorm.Association, association Association) {
+// replaceAssociation replaces association with associationValue. Entities removed from it are deleted
+// with orphan removal, and unlinked otherwise.
+func (u *GormRepository[T, ID]) replaceAssociation(associationValue reflect.Value, ass *gorm.Association, association associationMetadata) {
+	if association.cascade.has(CascadeOrphanRemoval) {
+		ass = ass.Unscoped()
+	}
 	if associationValue.IsZero() {
-		if err := ass.Unscoped().Clear(); err != nil {
+		if err := ass.Clear(); err != nil {
 			panic(err)
 		}
 	} else {
 		if association.Type == HasOne {
 			value := associationValue.Interface()
-			if err := ass.Unscoped().Replace(&value); err != nil {
+			if err := ass.Replace(&value); err != nil {
 				panic(err)
 			}
 		} else {
-			if err := ass.Unscoped().Replace(associationValue.Interface()); err != nil {
+			if err := ass.Replace(associationValue.Interface()); err != nil {
 				panic(err)
 			}
 		}
@@ -362,23 +691,30 @@ func (u *GormRepository[T, ID]) clearAssociations(ctx context.Context, entity T)
 	db := u.getGormDB(ctx)
 	var updated T
 
-	associations := findAssociations(&entity)
 	updated = entity
+	ptrToUpdated := ptrToConcrete(&updated)
 
-	for _, ass := range associations {
-		association := db.Unscoped().Model(&updated).Association(ass.Name)
+	for _, ass := range associationsOf(ptrToUpdated) {
+		if isPolymorphicBelongTo(ass.Association) {
+			continue // not an association of GORM
+		}
+		association := db.Unscoped().Model(ptrToUpdated).Association(ass.Name)
 		if association.Error != nil {
 			panic(association.Error)
 		}
 		logrus.Debugf("GormRepository.Update: Association %s %s", association.Relationship.Type, ass.Name)
+		// associated entities are deleted with remove or orphan removal, and unlinked otherwise
+		if ass.cascade&(CascadeRemove|CascadeOrphanRemoval) != 0 {
+			association = association.Unscoped()
+		}
 		switch association.Relationship.Type {
 		case schema.BelongsTo:
 		case schema.HasOne:
-			if err := association.Unscoped().Clear(); err != nil {
+			if err := association.Clear(); err != nil {
 				panic(err)
 			}
 		case schema.HasMany:
-			if err := association.Unscoped().Clear(); err != nil {
+			if err := association.Clear(); err != nil {
 				panic(err)
 			}
 		case schema.Many2Many:
@@ -391,12 +727,29 @@ func (u *GormRepository[T, ID]) clearAssociations(ctx context.Context, entity T)
 }
 
 func (u *GormRepository[T, ID]) Delete(ctx context.Context, entity T) error {
-	db := u.getGormDB(ctx)
-	if _, zero := findID[T, ID](entity); zero {
+	id, zero := findID[T, ID](entity)
+	if zero {
 		panic("entity.ID is missing")
 	}
+	u.evict(ctx, entity)
+	if uow, ok := unitOfWorkFrom(ctx); ok {
+		entityType := reflect.TypeOf(ptrToConcrete(&entity)).Elem()
+		return uow.register(&unitOfWorkEntry{
+			key:   entityKey{entityType: entityType, id: id},
+			state: pendingDelete,
+			depth: belongToDepth(entityType, map[reflect.Type]bool{}),
+			flush: func(ctx context.Context) error {
+				return u.delete(ctx, entity)
+			},
+		})
+	}
+	return u.delete(ctx, entity)
+}
+
+func (u *GormRepository[T, ID]) delete(ctx context.Context, entity T) error {
+	db := u.getGormDB(ctx)
 	u.clearAssociations(ctx, entity)
-	if err := db.Delete(&entity).Error; err != nil {
+	if err := db.Delete(ptrToConcrete(&entity)).Error; err != nil {
 		return err
 	}
 	return nil
@@ -404,8 +757,16 @@ func (u *GormRepository[T, ID]) Delete(ctx context.Context, entity T) error {
 
 // GetLazyLoadFuncOfBelongTo returns entity returning function
 func (u *GormRepository[T, ID]) GetLazyLoadFuncOfBelongTo(ctx context.Context, ptrToEntity any, id any) func() (any, error) {
+	return u.getLazyLoadFuncOfBelongToBy(ctx, ptrToEntity, "", id)
+}
+
+// getLazyLoadFuncOfBelongToBy returns entity returning function, finding it by the referenced column, or ID if empty
+func (u *GormRepository[T, ID]) getLazyLoadFuncOfBelongToBy(ctx context.Context, ptrToEntity any, references string, id any) func() (any, error) {
 	logrus.Debugf("GormRepository.GetLazyLoadFuncOfBelongTo: entity [%p] [%+v] id[%v]", ptrToEntity, ptrToEntity, id)
 	return func() (any, error) {
+		if err := checkLazyLoadScope(ctx, ptrToEntity); err != nil {
+			return nil, err
+		}
 		if id == nil {
 			return nil, nil
 		}
@@ -413,46 +774,94 @@ func (u *GormRepository[T, ID]) GetLazyLoadFuncOfBelongTo(ctx context.Context, p
 		if idValue.IsZero() {
 			return nil, nil
 		}
-		if found, err := u.findOne(ctx, ptrToEntity, id); err != nil {
-			return nil, err
+		var found any
+		var err error
+		if references == "" {
+			found, err = u.findOne(ctx, ptrToEntity, id)
 		} else {
-			return found, nil
+			found, err = u.findOneByForeignKey(ctx, ptrToEntity, map[string]any{references: id}, id)
+		}
+		if err != nil {
+			return nil, err
 		}
+		return found, nil
 	}
 }
 
 // GetLazyLoadFuncOfHasOne returns entity returning function
 func (u *GormRepository[T, ID]) GetLazyLoadFuncOfHasOne(ctx context.Context, ptrToEntity any, foreignKey string, foreignKeyValue any) func() (any, error) {
-	logrus.Debugf("GormRepository.GetLazyLoadFuncOfHasOne: entity [%p] [%+v] foreignKey[%s:%v]", ptrToEntity, ptrToEntity, foreignKey, foreignKeyValue)
+	return u.getLazyLoadFuncOfHasOneBy(ctx, ptrToEntity, map[string]any{foreignKey: foreignKeyValue}, foreignKeyValue)
+}
+
+func (u *GormRepository[T, ID]) getLazyLoadFuncOfHasOneBy(ctx context.Context, ptrToEntity any, conditions map[string]any, foreignKeyValue any) func() (any, error) {
+	logrus.Debugf("GormRepository.GetLazyLoadFuncOfHasOne: entity [%p] [%+v] conditions[%v]", ptrToEntity, ptrToEntity, conditions)
 	return func() (any, error) {
-		var err error
-		if ptrToEntity, err = u.findOneByForeignKey(ctx, ptrToEntity, foreignKey, foreignKeyValue); err != nil {
+		if err := checkLazyLoadScope(ctx, ptrToEntity); err != nil {
+			return nil, err
+		}
+		// the result is kept only on success, for a failed load function to be retried
+		found, err := u.findOneByForeignKey(ctx, ptrToEntity, conditions, foreignKeyValue)
+		if err != nil {
 			return nil, err
 		}
+		ptrToEntity = found
 		return ptrToEntity, nil
 	}
 }
 
 // GetLazyLoadFuncOfHasMany returns slice of entity returning function
 func (u *GormRepository[T, ID]) GetLazyLoadFuncOfHasMany(ctx context.Context, ptrToEntity any, foreignKey string, foreignKeyValue any) func() (any, error) {
-	logrus.Debugf("GormRepository.GetLazyLoadFuncOfHasMany: entity [%p] [%+v] foreignKey[%s:%v]", ptrToEntity, ptrToEntity, foreignKey, foreignKeyValue)
+	return u.getLazyLoadFuncOfHasManyBy(ctx, ptrToEntity, map[string]any{foreignKey: foreignKeyValue}, foreignKeyValue)
+}
+
+func (u *GormRepository[T, ID]) getLazyLoadFuncOfHasManyBy(ctx context.Context, ptrToEntity any, conditions map[string]any, foreignKeyValue any) func() (any, error) {
+	logrus.Debugf("GormRepository.GetLazyLoadFuncOfHasMany: entity [%p] [%+v] conditions[%v]", ptrToEntity, ptrToEntity, conditions)
 	return func() (any, error) {
-		var err error
-		if ptrToEntity, err = u.findByForeignKey(ctx, ptrToEntity, foreignKey, foreignKeyValue); err != nil {
+		if err := checkLazyLoadScope(ctx, ptrToEntity); err != nil {
 			return nil, err
 		}
+		// the result is kept only on success, for a failed load function to be retried
+		found, err := u.findByForeignKey(ctx, ptrToEntity, conditions, foreignKeyValue)
+		if err != nil {
+			return nil, err
+		}
+		ptrToEntity = found
 		return ptrToEntity, nil
 	}
 }
 
+// getLazyLoadFuncOfPolymorphic returns entity returning function of polymorphic belong-to association,
+// whose entity type is decided by the type field.
+func (u *GormRepository[T, ID]) getLazyLoadFuncOfPolymorphic(ctx context.Context, ptrToEntity any, association Association) func() (any, error) {
+	typeValue := findIDValue(ptrToEntity, association.Polymorphic+"Type")
+	logrus.Debugf("GormRepository.getLazyLoadFuncOfPolymorphic: entity [%p] type[%v] id[%v]", ptrToEntity, typeValue, association.ID)
+	return func() (any, error) {
+		if association.ID == nil || typeValue == nil {
+			return nil, nil
+		}
+		if association.PtrToEntity == nil {
+			return nil, fmt.Errorf("%s of %T has not registered polymorphic type %v", association.Name, ptrToEntity, typeValue)
+		}
+		if err := checkLazyLoadScope(ctx, association.PtrToEntity); err != nil {
+			return nil, err
+		}
+		return u.findOne(ctx, association.PtrToEntity, association.ID)
+	}
+}
+
 // GetLazyLoadFuncOfManyMany returns slice of entity returning function
 func (u *GormRepository[T, ID]) GetLazyLoadFuncOfManyMany(ctx context.Context, ptrToParent any, ptrToChildren any, associationName string, foreignKey string, foreignKeyValue any) func() (any, error) {
 	logrus.Debugf("GormRepository.GetLazyLoadFuncOfManyMany: entity [%p] [%+v] foreignKey[%s:%v]", ptrToChildren, ptrToChildren, foreignKey, foreignKeyValue)
 	return func() (any, error) {
-		var err error
-		if ptrToChildren, err = u.findAssociationsByForeignKey(ctx, ptrToParent, ptrToChildren, associationName, foreignKey, foreignKeyValue); err != nil {
+		if err := checkLazyLoadScope(ctx, ptrToChildren); err != nil {
+			return nil, err
+		}
+		// the result is kept only on success, for a failed load function to be retried
+		found, err := u.findAssociationsByForeignKey(ctx, ptrToParent, ptrToChildren, associationName, foreignKey, foreignKeyValue)
+		if err != nil {
 			return nil, err
 		}
+		ptrToChildren = found
 		return ptrToChildren, nil
 	}
 }
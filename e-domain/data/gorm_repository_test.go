@@ -13,6 +13,7 @@ import (
 	"gorm.io/gorm/logger"
 	"log"
 	"os"
+	"path/filepath"
 	"testing"
 	"time"
 )
@@ -42,6 +43,20 @@ func getGormDB() *gorm.DB {
 	return db
 }
 
+// getGormFileDB returns *gorm.DB of a temporary database file, which survives a connection discarded by database/sql
+// unlike in-memory database.
+func getGormFileDB(t *testing.T) *gorm.DB {
+	db, err := gorm.Open(sqlite.Open(filepath.Join(t.TempDir(), "gorm_repo.db?_foreign_keys=on")), &gorm.Config{
+		NowFunc: func() time.Time {
+			return time.Now().UTC()
+		},
+	})
+	if err != nil {
+		panic("failed to connect database")
+	}
+	return db
+}
+
 func TestGormRepository_Simple(t *testing.T) {
 	type User struct {
 		ID           uint
@@ -687,6 +702,26 @@ func TestGormRepository_HasOne_Lazy(t *testing.T) {
 		assert.Nil(t, err)
 		assert.Equal(t, created.CreditCard, creditCard)
 	})
+	t.Run("retry after not found", func(t *testing.T) {
+		ctx := context.Background()
+
+		created, err := userRepository.Create(ctx, User{Name: "ryan"})
+		assert.Nil(t, err)
+		found, err := userRepository.FindOne(ctx, created.ID)
+		assert.Nil(t, err)
+
+		_, err = data.LazyLoadNow[CreditCard]("CreditCard", &found)
+		assert.ErrorIs(t, err, data.NotFoundError)
+		_, err = data.LazyLoadNow[CreditCard]("CreditCard", &found)
+		assert.ErrorIs(t, err, data.NotFoundError)
+
+		creditCard := CreditCard{Number: "567856785678", UserID: created.ID}
+		db.Create(&creditCard)
+		loaded, err := data.LazyLoadNow[CreditCard]("CreditCard", &found)
+		assert.Nil(t, err)
+		assert.Equal(t, creditCard.ID, loaded.ID)
+		assert.Equal(t, creditCard.Number, loaded.Number)
+	})
 	t.Run("find by", func(t *testing.T) {
 		ctx := context.Background()
 
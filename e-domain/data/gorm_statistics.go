@@ -0,0 +1,125 @@
+package data
+
+import (
+	"context"
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+	"regexp"
+	"sort"
+	"sync"
+)
+
+// SQLStatistics collects SQL statements issued with a context by WithSQLStatistics.
+// Statements are collected from *gorm.DB registered by RegisterSQLStatistics, or by GormTransactionManager with WithStatistics.
+type SQLStatistics struct {
+	m          sync.Mutex
+	parent     *SQLStatistics
+	statements []string
+	shapes     map[string]int
+}
+
+// RepeatedStatement is a statement shape issued repeatedly, e.g. by lazy loads of a loop, known as N+1 queries.
+type RepeatedStatement struct {
+	Shape string
+	Count int
+}
+
+type sqlStatisticsKey struct{}
+
+// WithSQLStatistics returns a context collecting statements into the returned SQLStatistics.
+// Statements are also collected into SQLStatistics of ctx, if any.
+func WithSQLStatistics(ctx context.Context) (context.Context, *SQLStatistics) {
+	parent, _ := ctx.Value(sqlStatisticsKey{}).(*SQLStatistics)
+	stats := &SQLStatistics{parent: parent, shapes: make(map[string]int)}
+	return context.WithValue(ctx, sqlStatisticsKey{}, stats), stats
+}
+
+// placeholders matches a list of placeholders such as "IN (?,?,?)", which is normalized to one.
+var placeholders = regexp.MustCompile(`\(\?(\s*,\s*\?)*\)`)
+
+func statementShape(sql string) string {
+	return placeholders.ReplaceAllString(sql, "(?)")
+}
+
+func (s *SQLStatistics) record(sql string) {
+	shape := statementShape(sql)
+	for stats := s; stats != nil; stats = stats.parent {
+		stats.m.Lock()
+		stats.statements = append(stats.statements, sql)
+		stats.shapes[shape]++
+		stats.m.Unlock()
+	}
+}
+
+// Count returns the number of collected statements.
+func (s *SQLStatistics) Count() int {
+	s.m.Lock()
+	defer s.m.Unlock()
+	return len(s.statements)
+}
+
+// Statements returns collected statements in issued order.
+func (s *SQLStatistics) Statements() []string {
+	s.m.Lock()
+	defer s.m.Unlock()
+	return append([]string{}, s.statements...)
+}
+
+// Repeated returns statement shapes issued at least min times, most repeated first.
+// Statements differing only in values or the number of IN placeholders have the same shape.
+func (s *SQLStatistics) Repeated(min int) []RepeatedStatement {
+	s.m.Lock()
+	defer s.m.Unlock()
+	var repeated []RepeatedStatement
+	for shape, count := range s.shapes {
+		if count >= min {
+			repeated = append(repeated, RepeatedStatement{Shape: shape, Count: count})
+		}
+	}
+	sort.Slice(repeated, func(i, j int) bool {
+		if repeated[i].Count != repeated[j].Count {
+			return repeated[i].Count > repeated[j].Count
+		}
+		return repeated[i].Shape < repeated[j].Shape
+	})
+	return repeated
+}
+
+func (s *SQLStatistics) Reset() {
+	s.m.Lock()
+	defer s.m.Unlock()
+	s.statements = nil
+	s.shapes = make(map[string]int)
+}
+
+const sqlStatisticsCallback = "data:sql_statistics"
+
+// RegisterSQLStatistics registers callbacks of db collecting statements into SQLStatistics of the statement context.
+func RegisterSQLStatistics(db *gorm.DB) error {
+	if db.Callback().Query().Get(sqlStatisticsCallback) != nil {
+		return nil
+	}
+	record := func(db *gorm.DB) {
+		if db.Statement.Context == nil {
+			return
+		}
+		if stats, ok := db.Statement.Context.Value(sqlStatisticsKey{}).(*SQLStatistics); ok && db.Statement.SQL.Len() > 0 {
+			stats.record(db.Statement.SQL.String())
+		}
+	}
+	callbacks := []error{
+		db.Callback().Create().After("gorm:create").Register(sqlStatisticsCallback, record),
+		db.Callback().Query().After("gorm:query").Register(sqlStatisticsCallback, record),
+		db.Callback().Update().After("gorm:update").Register(sqlStatisticsCallback, record),
+		db.Callback().Delete().After("gorm:delete").Register(sqlStatisticsCallback, record),
+		db.Callback().Row().After("gorm:row").Register(sqlStatisticsCallback, record),
+		db.Callback().Raw().After("gorm:raw").Register(sqlStatisticsCallback, record),
+	}
+	for _, err := range callbacks {
+		if err != nil {
+			logrus.Errorf("RegisterSQLStatistics: fail to register callback - %v", err)
+			return err
+		}
+	}
+	return nil
+}
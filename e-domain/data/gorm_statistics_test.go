@@ -0,0 +1,84 @@
+package data_test
+
+import (
+	"context"
+	"github.com/reuben-baek/go-learning/e-domain/data"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestSQLStatistics(t *testing.T) {
+	type Company struct {
+		ID   uint
+		Name string
+	}
+	type Product struct {
+		data.LazyLoader `gorm:"-"`
+		ID              uint
+		Name            string
+		CompanyID       uint
+		Company         Company
+	}
+
+	db := getGormDB()
+	db.AutoMigrate(&Company{}, &Product{})
+
+	transactionManager := data.NewGormTransactionManager(db, data.WithStatistics())
+	companyRepository := data.NewGormRepository[Company, uint](transactionManager)
+	productRepository := data.NewGormRepository[Product, uint](transactionManager)
+
+	ctx := context.Background()
+	var products []Product
+	for _, name := range []string{"kakao", "naver", "line"} {
+		company, _ := companyRepository.Create(ctx, Company{Name: name})
+		product, _ := productRepository.Create(ctx, Product{Name: name + "-storage", CompanyID: company.ID})
+		products = append(products, product)
+	}
+
+	t.Run("lazy loads in a loop", func(t *testing.T) {
+		ctx, stats := data.WithSQLStatistics(ctx)
+		err := transactionManager.Do(ctx, func(ctx context.Context) error {
+			for _, product := range products {
+				found, err := productRepository.FindOne(ctx, product.ID)
+				if err != nil {
+					return err
+				}
+				if _, err := data.LazyLoadNow[Company]("Company", &found); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+		assert.Nil(t, err)
+		assert.Equal(t, 6, stats.Count())
+		assert.Equal(t, []data.RepeatedStatement{
+			{Shape: "SELECT * FROM `companies` WHERE id = ? ORDER BY `companies`.`id` LIMIT 1", Count: 3},
+			{Shape: "SELECT * FROM `products` WHERE id = ? ORDER BY `products`.`id` LIMIT 1", Count: 3},
+		}, stats.Repeated(2))
+	})
+	t.Run("nested", func(t *testing.T) {
+		ctx, outer := data.WithSQLStatistics(ctx)
+		productRepository.FindOne(ctx, products[0].ID)
+		inner, stats := data.WithSQLStatistics(ctx)
+		productRepository.FindOne(inner, products[1].ID)
+		companyRepository.FindOne(context.Background(), products[1].CompanyID) // not collected
+
+		assert.Equal(t, 1, stats.Count())
+		assert.Equal(t, 2, outer.Count())
+		assert.Equal(t, []data.RepeatedStatement{
+			{Shape: "SELECT * FROM `products` WHERE id = ? ORDER BY `products`.`id` LIMIT 1", Count: 2},
+		}, outer.Repeated(2))
+
+		outer.Reset()
+		assert.Equal(t, 0, outer.Count())
+	})
+	t.Run("shape of IN", func(t *testing.T) {
+		ctx, stats := data.WithSQLStatistics(ctx)
+		var found []Company
+		db.WithContext(ctx).Find(&found, "id IN ?", []uint{1, 2})
+		db.WithContext(ctx).Find(&found, "id IN ?", []uint{1, 2, 3})
+		assert.Equal(t, []data.RepeatedStatement{
+			{Shape: "SELECT * FROM `companies` WHERE id IN (?)", Count: 2},
+		}, stats.Repeated(2))
+	})
+}
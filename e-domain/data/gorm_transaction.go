@@ -2,47 +2,293 @@ package data
 
 import (
 	"context"
+	"database/sql"
+	"errors"
+	"fmt"
 	"github.com/sirupsen/logrus"
 	"gorm.io/gorm"
+	"runtime/debug"
+	"sync/atomic"
+	"time"
 )
 
+const DefaultDataSource = "default"
+
 type GormTransactionManager struct {
-	db *gorm.DB
+	dataSources   map[string]*gorm.DB
+	names         []string // registration order, which is also the commit order
+	recoverPanics bool
+	timeout       time.Duration
+	statistics    bool
+}
+
+type GormTransactionOption func(g *GormTransactionManager)
+
+// WithPanicRecovery makes Do recover a panic of f and return it as *PanicError after rollback,
+// instead of letting it propagate.
+func WithPanicRecovery() GormTransactionOption {
+	return func(g *GormTransactionManager) {
+		g.recoverPanics = true
+	}
+}
+
+// WithDefaultTimeout sets the timeout of transactions, which is overridden by WithTransactionTimeout.
+func WithDefaultTimeout(timeout time.Duration) GormTransactionOption {
+	return func(g *GormTransactionManager) {
+		g.timeout = timeout
+	}
+}
+
+// WithStatistics registers callbacks of the datasources collecting statements into SQLStatistics of contexts.
+func WithStatistics() GormTransactionOption {
+	return func(g *GormTransactionManager) {
+		g.statistics = true
+	}
+}
+
+// WithDataSource registers db as a named datasource besides the default one.
+func WithDataSource(name string, db *gorm.DB) GormTransactionOption {
+	return func(g *GormTransactionManager) {
+		if _, ok := g.dataSources[name]; ok {
+			panic(fmt.Sprintf("GormTransactionManager: datasource '%s' is already registered", name))
+		}
+		g.dataSources[name] = db
+		g.names = append(g.names, name)
+	}
 }
 
-func NewGormTransactionManager(db *gorm.DB) *GormTransactionManager {
-	return &GormTransactionManager{db: db}
+func NewGormTransactionManager(db *gorm.DB, options ...GormTransactionOption) *GormTransactionManager {
+	g := &GormTransactionManager{
+		dataSources: map[string]*gorm.DB{DefaultDataSource: db},
+		names:       []string{DefaultDataSource},
+	}
+	for _, option := range options {
+		option(g)
+	}
+	if g.statistics {
+		for _, name := range g.names {
+			if err := RegisterSQLStatistics(g.dataSources[name]); err != nil {
+				panic(fmt.Sprintf("NewGormTransactionManager: fail to register statistics on '%s' - %v", name, err))
+			}
+		}
+	}
+	return g
 }
 
-type gormTransactionKey struct{}
+type gormTransactionKey struct {
+	dataSource string
+}
 
+type gormTransactionDataSourcesKey struct{}
+
+// gormTransaction is the transaction session bound to the context of Do.
+// done is set when Do returns, to detect the context leaked out of Do.
+type gormTransaction struct {
+	dataSource string
+	tx         *gorm.DB
+	done       atomic.Bool
+}
+
+// PartialCommitError is returned by DoOn when some datasources have committed and another has failed to commit.
+type PartialCommitError struct {
+	Committed  []string
+	Failed     string
+	RolledBack []string
+	Err        error
+}
+
+func (e *PartialCommitError) Error() string {
+	return fmt.Sprintf("partial commit: committed %v, fail to commit '%s' - %v, rolled back %v", e.Committed, e.Failed, e.Err, e.RolledBack)
+}
+
+func (e *PartialCommitError) Unwrap() error {
+	return e.Err
+}
+
+func (g *GormTransactionManager) DataSources() []string {
+	return append([]string{}, g.names...)
+}
+
+func (g *GormTransactionManager) dataSource(name string) *gorm.DB {
+	db, ok := g.dataSources[name]
+	if !ok {
+		panic(fmt.Sprintf("GormTransactionManager: unknown datasource '%s', registered datasources are %v", name, g.names))
+	}
+	return db
+}
+
+// Do runs f in a transaction of the default datasource.
 func (g *GormTransactionManager) Do(ctx context.Context, f func(ctx context.Context) error) error {
-	tx := g.db.Begin().WithContext(ctx)
-	newCtx := context.WithValue(ctx, gormTransactionKey{}, tx)
+	return g.DoOn(ctx, []string{DefaultDataSource}, f)
+}
+
+// DoOn runs f in transactions of the given datasources.
+// The transactions are committed one by one in registration order of the datasources. It is not an atomic commit;
+// if a datasource fails to commit after others have committed, *PartialCommitError is returned
+// and AfterRollback callbacks are called instead of AfterCommit callbacks.
+func (g *GormTransactionManager) DoOn(ctx context.Context, dataSources []string, f func(ctx context.Context) error) (err error) {
+	if len(dataSources) == 0 {
+		panic("GormTransactionManager.DoOn: no datasource")
+	}
+	// without timeout, transactions are not bound to ctx, and cancellation of ctx fails statements of f.
+	txCtx, beginCtx := ctx, context.Background()
+	if timeout := transactionTimeout(ctx, g.timeout); timeout > 0 {
+		var cancel context.CancelFunc
+		txCtx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+		beginCtx = txCtx
+	}
+	transactions, err := g.begin(beginCtx, dataSources)
+	if err != nil {
+		return err
+	}
+	newCtx := context.WithValue(txCtx, gormTransactionDataSourcesKey{}, dataSources)
+	for _, transaction := range transactions {
+		newCtx = context.WithValue(newCtx, gormTransactionKey{dataSource: transaction.dataSource}, transaction)
+	}
+	newCtx, synchronization := withTransactionSynchronization(newCtx)
+	defer func() {
+		for _, transaction := range transactions {
+			transaction.done.Store(true)
+		}
+	}()
 
 	panicked := true
 	defer func() {
-		if panicked {
-			tx.Rollback()
+		if !panicked {
+			return
+		}
+		if !g.recoverPanics {
+			// the panic is not recovered, and propagates with its original stack after rollback.
+			g.rollback(transactions)
+			synchronization.triggerAfterRollback(ctx)
+			return
+		}
+		r := recover()
+		rollbackErr := g.rollback(transactions)
+		synchronization.triggerAfterRollback(ctx)
+		if r == nil { // runtime.Goexit
+			return
+		}
+		panicErr := &PanicError{Value: r, Stack: debug.Stack()}
+		logrus.Errorf("GormTransactionManager.Do: recovered panic - %v\n%s", r, panicErr.Stack)
+		if rollbackErr != nil {
+			err = &TransactionError{Op: TransactionRollback, Err: rollbackErr, Cause: panicErr}
+		} else {
+			err = panicErr
 		}
 	}()
 
-	err := f(newCtx)
+	err = f(newCtx)
+	if err == nil {
+		err = synchronization.triggerBeforeCommit(newCtx)
+	}
+	if err == nil {
+		// the transaction has already been rolled back by database/sql if the timeout of txCtx has expired.
+		err = txCtx.Err()
+	}
 	panicked = false // if f is panicked, this statement is not executed.
 
 	if err != nil {
-		tx.Rollback()
+		rollbackErr := g.rollback(transactions)
+		synchronization.triggerAfterRollback(ctx)
+		if rollbackErr != nil {
+			return &TransactionError{Op: TransactionRollback, Err: rollbackErr, Cause: err}
+		}
+		return err
+	}
+	if err = g.commit(transactions); err != nil {
+		synchronization.triggerAfterRollback(ctx)
 		return err
 	}
-	tx.Commit()
+	synchronization.triggerAfterCommit(ctx)
+	return nil
+}
+
+func (g *GormTransactionManager) begin(ctx context.Context, dataSources []string) ([]*gormTransaction, error) {
+	for _, name := range dataSources {
+		g.dataSource(name) // panics on an unknown datasource
+	}
+	var transactions []*gormTransaction
+	for _, name := range g.names {
+		if !contains(dataSources, name) {
+			continue
+		}
+		tx := g.dataSource(name).WithContext(ctx).Begin()
+		if tx.Error != nil {
+			logrus.Errorf("GormTransactionManager.Do: fail to begin on '%s' - %v", name, tx.Error)
+			g.rollback(transactions)
+			return nil, &TransactionError{Op: TransactionBegin, Err: fmt.Errorf("datasource '%s': %w", name, tx.Error)}
+		}
+		transactions = append(transactions, &gormTransaction{dataSource: name, tx: tx})
+	}
+	return transactions, nil
+}
+
+func (g *GormTransactionManager) commit(transactions []*gormTransaction) error {
+	var committed []string
+	for i, transaction := range transactions {
+		if err := transaction.tx.Commit().Error; err != nil {
+			logrus.Errorf("GormTransactionManager.Do: fail to commit '%s' - %v", transaction.dataSource, err)
+			rest := transactions[i+1:]
+			g.rollback(rest)
+			if len(committed) == 0 {
+				return &TransactionError{Op: TransactionCommit, Err: err}
+			}
+			rolledBack := make([]string, 0, len(rest))
+			for _, v := range rest {
+				rolledBack = append(rolledBack, v.dataSource)
+			}
+			return &PartialCommitError{Committed: committed, Failed: transaction.dataSource, RolledBack: rolledBack, Err: err}
+		}
+		committed = append(committed, transaction.dataSource)
+	}
 	return nil
 }
 
+func (g *GormTransactionManager) rollback(transactions []*gormTransaction) error {
+	var errs []error
+	for _, transaction := range transactions {
+		err := transaction.tx.Rollback().Error
+		if err != nil && !errors.Is(err, sql.ErrTxDone) {
+			logrus.Errorf("GormTransactionManager.Do: fail to rollback '%s' - %v", transaction.dataSource, err)
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Get returns *gorm.DB of the default datasource.
 func (g *GormTransactionManager) Get(ctx context.Context) any {
-	tx, ok := ctx.Value(gormTransactionKey{}).(*gorm.DB)
+	return g.GetDataSource(ctx, DefaultDataSource)
+}
+
+// GetDataSource returns *gorm.DB of the named datasource.
+func (g *GormTransactionManager) GetDataSource(ctx context.Context, dataSource string) any {
+	db := g.dataSource(dataSource)
+	transaction, ok := ctx.Value(gormTransactionKey{dataSource: dataSource}).(*gormTransaction)
 	if !ok {
-		logrus.Warnf("GormTransactionManager.Get: no transaction session")
-		tx = g.db.WithContext(ctx)
+		if dataSources, ok := ctx.Value(gormTransactionDataSourcesKey{}).([]string); ok {
+			logrus.Warnf("GormTransactionManager.Get: no transaction session on datasource '%s', the transaction is on %v", dataSource, dataSources)
+		} else {
+			logrus.Warnf("GormTransactionManager.Get: no transaction session")
+		}
+		return db.WithContext(ctx)
+	}
+	if transaction.done.Load() {
+		logrus.Errorf("GormTransactionManager.Get: %v\n%s", TransactionClosedError, debug.Stack())
+		closed := transaction.tx.Session(&gorm.Session{})
+		closed.AddError(TransactionClosedError)
+		return closed
+	}
+	return transaction.tx.WithContext(ctx)
+}
+
+func contains(names []string, name string) bool {
+	for _, v := range names {
+		if v == name {
+			return true
+		}
 	}
-	return tx
+	return false
 }
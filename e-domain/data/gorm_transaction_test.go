@@ -2,11 +2,16 @@ package data_test
 
 import (
 	"context"
+	"database/sql"
 	"errors"
 	"fmt"
 	"github.com/reuben-baek/go-learning/e-domain/data"
 	"github.com/stretchr/testify/assert"
+	"gorm.io/driver/sqlite"
 	"gorm.io/gorm"
+	"path/filepath"
+	"runtime/debug"
+	"strings"
 	"testing"
 	"time"
 )
@@ -108,4 +113,276 @@ func TestGormTransactionManager(t *testing.T) {
 		assert.Equal(t, data.NotFoundError, err)
 		assert.Empty(t, found)
 	})
+	t.Run("panic propagates from f", func(t *testing.T) {
+		ctx := context.Background()
+		var created User
+		var stack string
+		func() {
+			defer func() {
+				if r := recover(); r != nil {
+					stack = string(debug.Stack())
+				}
+			}()
+			transactionManager.Do(ctx, func(ctx context.Context) error {
+				created, _ = userRepository.Create(ctx, User{Name: "reuben.b"})
+				panic("something wrong")
+			})
+		}()
+		// the innermost panic is of f, not re-panicked by Do
+		lines := strings.Split(stack, "\n")
+		for i, line := range lines {
+			if strings.HasPrefix(line, "panic(") {
+				assert.Contains(t, lines[i+3], "gorm_transaction_test.go")
+				break
+			}
+		}
+
+		_, err := userRepository.FindOne(ctx, created.ID)
+		assert.Equal(t, data.NotFoundError, err)
+	})
+	t.Run("panic recovery", func(t *testing.T) {
+		transactionManager := data.NewGormTransactionManager(db, data.WithPanicRecovery())
+		ctx := context.Background()
+		var created User
+		err := transactionManager.Do(ctx, func(ctx context.Context) error {
+			created, _ = userRepository.Create(ctx, User{Name: "reuben.b"})
+			panic("something wrong")
+		})
+		var panicErr *data.PanicError
+		assert.ErrorAs(t, err, &panicErr)
+		assert.Equal(t, "something wrong", panicErr.Value)
+		assert.NotEmpty(t, panicErr.Stack)
+
+		_, err = userRepository.FindOne(ctx, created.ID)
+		assert.Equal(t, data.NotFoundError, err)
+	})
+	t.Run("commit error", func(t *testing.T) {
+		ctx := context.Background()
+		err := transactionManager.Do(ctx, func(ctx context.Context) error {
+			// commit the session behind the manager's back
+			return transactionManager.Get(ctx).(*gorm.DB).Commit().Error
+		})
+		var transactionErr *data.TransactionError
+		assert.ErrorAs(t, err, &transactionErr)
+		assert.Equal(t, data.TransactionCommit, transactionErr.Op)
+		assert.ErrorIs(t, err, sql.ErrTxDone)
+	})
+	t.Run("begin error", func(t *testing.T) {
+		closedDB := getGormDB()
+		sqlDB, _ := closedDB.DB()
+		sqlDB.Close()
+
+		called := false
+		err := data.NewGormTransactionManager(closedDB).Do(context.Background(), func(ctx context.Context) error {
+			called = true
+			return nil
+		})
+		var transactionErr *data.TransactionError
+		assert.ErrorAs(t, err, &transactionErr)
+		assert.Equal(t, data.TransactionBegin, transactionErr.Op)
+		assert.False(t, called)
+	})
+	t.Run("leaked transaction context", func(t *testing.T) {
+		var leakedCtx context.Context
+		err := transactionManager.Do(context.Background(), func(ctx context.Context) error {
+			leakedCtx = ctx
+			return nil
+		})
+		assert.Nil(t, err)
+
+		_, err = userRepository.Create(leakedCtx, User{Name: "reuben.b"})
+		assert.ErrorIs(t, err, data.TransactionClosedError)
+	})
+}
+
+func TestGormTransactionManager_MultiDataSource(t *testing.T) {
+	type Order struct {
+		ID   uint
+		Item string
+	}
+	type Stock struct {
+		ID       uint
+		Item     string
+		Quantity int
+	}
+
+	dir := t.TempDir()
+	ordersDB, _ := gorm.Open(sqlite.Open(filepath.Join(dir, "orders.db")), &gorm.Config{})
+	inventoryDB, _ := gorm.Open(sqlite.Open(filepath.Join(dir, "inventory.db")), &gorm.Config{})
+	ordersDB.AutoMigrate(&Order{})
+	inventoryDB.AutoMigrate(&Stock{})
+
+	transactionManager := data.NewGormTransactionManager(getGormDB(),
+		data.WithDataSource("orders", ordersDB),
+		data.WithDataSource("inventory", inventoryDB),
+	)
+	orderRepository := data.NewGormRepositoryOn[Order, uint](transactionManager, "orders")
+	stockRepository := data.NewGormRepositoryOn[Stock, uint](transactionManager, "inventory")
+	assert.Equal(t, []string{data.DefaultDataSource, "orders", "inventory"}, transactionManager.DataSources())
+
+	ctx := context.Background()
+	placeOrder := func(ctx context.Context) (Order, Stock, error) {
+		order, err := orderRepository.Create(ctx, Order{Item: "macbook"})
+		if err != nil {
+			return order, Stock{}, err
+		}
+		stock, err := stockRepository.Create(ctx, Stock{Item: "macbook", Quantity: -1})
+		return order, stock, err
+	}
+
+	t.Run("commit", func(t *testing.T) {
+		var order Order
+		var stock Stock
+		err := transactionManager.DoOn(ctx, []string{"orders", "inventory"}, func(ctx context.Context) error {
+			var err error
+			order, stock, err = placeOrder(ctx)
+			return err
+		})
+		assert.Nil(t, err)
+
+		var count int64
+		ordersDB.Model(&Order{}).Where("id = ?", order.ID).Count(&count)
+		assert.Equal(t, int64(1), count)
+		inventoryDB.Model(&Stock{}).Where("id = ?", stock.ID).Count(&count)
+		assert.Equal(t, int64(1), count)
+	})
+	t.Run("rollback", func(t *testing.T) {
+		var order Order
+		var stock Stock
+		err := transactionManager.DoOn(ctx, []string{"orders", "inventory"}, func(ctx context.Context) error {
+			order, stock, _ = placeOrder(ctx)
+			return errors.New("fail to place order")
+		})
+		assert.NotNil(t, err)
+
+		_, err = orderRepository.FindOne(ctx, order.ID)
+		assert.Equal(t, data.NotFoundError, err)
+		_, err = stockRepository.FindOne(ctx, stock.ID)
+		assert.Equal(t, data.NotFoundError, err)
+	})
+	t.Run("partial commit", func(t *testing.T) {
+		var order Order
+		var stock Stock
+		afterCommitCalled := false
+		err := transactionManager.DoOn(ctx, []string{"orders", "inventory"}, func(ctx context.Context) error {
+			order, stock, _ = placeOrder(ctx)
+			data.AfterCommit(ctx, func(ctx context.Context) {
+				afterCommitCalled = true
+			})
+			// inventory is committed behind the manager's back, so its commit fails after orders committed.
+			return transactionManager.GetDataSource(ctx, "inventory").(*gorm.DB).Commit().Error
+		})
+		var partialCommitErr *data.PartialCommitError
+		assert.ErrorAs(t, err, &partialCommitErr)
+		assert.Equal(t, []string{"orders"}, partialCommitErr.Committed)
+		assert.Equal(t, "inventory", partialCommitErr.Failed)
+		assert.ErrorIs(t, err, sql.ErrTxDone)
+		assert.False(t, afterCommitCalled)
+
+		_, err = orderRepository.FindOne(ctx, order.ID)
+		assert.Nil(t, err)
+		_, err = stockRepository.FindOne(ctx, stock.ID)
+		assert.Nil(t, err)
+	})
+	t.Run("datasource out of transaction", func(t *testing.T) {
+		var order Order
+		var stock Stock
+		err := transactionManager.DoOn(ctx, []string{"orders"}, func(ctx context.Context) error {
+			order, stock, _ = placeOrder(ctx)
+			return errors.New("fail to place order")
+		})
+		assert.NotNil(t, err)
+
+		_, err = orderRepository.FindOne(ctx, order.ID)
+		assert.Equal(t, data.NotFoundError, err)
+		_, err = stockRepository.FindOne(ctx, stock.ID)
+		assert.Nil(t, err)
+	})
+	t.Run("unknown datasource", func(t *testing.T) {
+		assert.Panics(t, func() {
+			transactionManager.DoOn(ctx, []string{"payments"}, func(ctx context.Context) error {
+				return nil
+			})
+		})
+	})
+}
+
+func TestGormTransactionManager_Timeout(t *testing.T) {
+	type Company struct {
+		ID   uint
+		Name string
+	}
+	type User struct {
+		data.LazyLoader `gorm:"-"`
+		ID              uint
+		Name            string
+		CompanyID       uint
+		Company         Company
+	}
+
+	db := getGormFileDB(t)
+	db.AutoMigrate(&Company{}, &User{})
+
+	transactionManager := data.NewGormTransactionManager(db, data.WithDefaultTimeout(20*time.Millisecond))
+	companyRepository := data.NewGormRepository[Company, uint](transactionManager)
+	userRepository := data.NewGormRepository[User, uint](transactionManager)
+	kakao, _ := companyRepository.Create(context.Background(), Company{Name: "kakao"})
+
+	t.Run("default timeout", func(t *testing.T) {
+		ctx := context.Background()
+		var created User
+		err := transactionManager.Do(ctx, func(ctx context.Context) error {
+			created, _ = userRepository.Create(ctx, User{Name: "reuben.b", CompanyID: kakao.ID})
+			<-ctx.Done()
+			return nil
+		})
+		assert.ErrorIs(t, err, context.DeadlineExceeded)
+
+		_, err = userRepository.FindOne(ctx, created.ID)
+		assert.Equal(t, data.NotFoundError, err)
+	})
+	t.Run("transaction timeout", func(t *testing.T) {
+		ctx := data.WithTransactionTimeout(context.Background(), 100*time.Millisecond)
+		err := transactionManager.Do(ctx, func(ctx context.Context) error {
+			time.Sleep(40 * time.Millisecond) // longer than the default timeout
+			_, err := userRepository.Create(ctx, User{Name: "reuben.b", CompanyID: kakao.ID})
+			return err
+		})
+		assert.Nil(t, err)
+
+		start := time.Now()
+		ctx = data.WithTransactionTimeout(context.Background(), 10*time.Millisecond)
+		err = transactionManager.Do(ctx, func(ctx context.Context) error {
+			<-ctx.Done()
+			return ctx.Err()
+		})
+		assert.ErrorIs(t, err, context.DeadlineExceeded)
+		assert.Less(t, time.Since(start), 200*time.Millisecond)
+	})
+	t.Run("lazy load after transaction", func(t *testing.T) {
+		ctx := context.Background()
+		reuben, _ := userRepository.Create(ctx, User{Name: "reuben.b", CompanyID: kakao.ID})
+
+		var found User
+		err := transactionManager.Do(ctx, func(ctx context.Context) error {
+			var err error
+			found, err = userRepository.FindOne(ctx, reuben.ID)
+			return err
+		})
+		assert.Nil(t, err)
+
+		_, err = data.LazyLoadNow[Company]("Company", &found)
+		var scopeErr *data.LazyLoadScopeError
+		assert.ErrorAs(t, err, &scopeErr)
+		assert.ErrorIs(t, err, data.TransactionClosedError)
+	})
+	t.Run("lazy load after context canceled", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		reuben, _ := userRepository.Create(ctx, User{Name: "reuben.b", CompanyID: kakao.ID})
+		found, _ := userRepository.FindOne(ctx, reuben.ID)
+		cancel()
+
+		_, err := data.LazyLoadNow[Company]("Company", &found)
+		assert.ErrorIs(t, err, context.Canceled)
+	})
 }
@@ -15,8 +15,8 @@ func findID[T any, ID comparable](entity T) (ID, bool) {
 	if valueOfEntity.Type().Kind() == reflect.Pointer {
 		valueOfEntity = reflect.Indirect(valueOfEntity)
 	}
-	value := valueOfEntity.FieldByName("ID")
-	if !value.IsValid() {
+	value, ok := metadataOf(valueOfEntity.Type()).field(valueOfEntity, "ID")
+	if !ok {
 		panic(fmt.Sprintf("Entity '%s' has not ID field", valueOfEntity.Type()))
 	}
 	if !value.Comparable() {
@@ -39,8 +39,8 @@ func findIDValue(ptrToEntity any, fieldName string) any {
 	if !valueOfEntity.IsValid() {
 		return nil
 	}
-	value := valueOfEntity.FieldByName(fieldName)
-	if !value.IsValid() {
+	value, ok := metadataOf(valueOfEntity.Type()).field(valueOfEntity, fieldName)
+	if !ok {
 		panic(fmt.Sprintf("Entity '%s' has not %s field", valueOfEntity.Type(), fieldName))
 	}
 	if value.Type().Kind() == reflect.Pointer && value.IsNil() {
@@ -52,11 +52,24 @@ func findIDValue(ptrToEntity any, fieldName string) any {
 	return value.Interface()
 }
 
+// idValueOf returns ID of ptrToEntity, or nil if it has no ID field, such as join entities of composite keys.
+func idValueOf(ptrToEntity any) any {
+	valueOfEntity := reflect.Indirect(reflect.ValueOf(ptrToEntity))
+	if !valueOfEntity.IsValid() {
+		return nil
+	}
+	if _, ok := metadataOf(valueOfEntity.Type()).fields["ID"]; !ok {
+		return nil
+	}
+	return findIDValue(ptrToEntity, "ID")
+}
+
 type FetchMode string
 
 const (
 	FetchEagerMode = "eager"
 	FetchLazyMode  = "lazy"
+	FetchBatchMode = "batch" // lazy, but loaded for all sibling entities fetched together at the first access
 )
 
 func ToFetchMode(m string) FetchMode {
@@ -65,6 +78,8 @@ func ToFetchMode(m string) FetchMode {
 		return FetchLazyMode
 	case FetchEagerMode:
 		return FetchEagerMode
+	case FetchBatchMode:
+		return FetchBatchMode
 	default:
 		panic(fmt.Sprintf("wrong fetch-mode - %s", m))
 	}
@@ -86,6 +101,82 @@ type Association struct {
 	ForeignKey  string
 	Type        AssociationType
 	FetchMode   FetchMode
+	// ForeignKeyField and References are fields given by assoc tag, empty for naming conventions.
+	ForeignKeyField string
+	References      string
+	// Polymorphic is the prefix of <Polymorphic>ID and <Polymorphic>Type fields of polymorphic associations.
+	Polymorphic string
+	// JoinEntity is set for has-many associations of join entities, which are many-to-many memberships with extra columns.
+	JoinEntity bool
+}
+
+// foreignKeyField returns the foreign key field, of entityType for belong-to, and of the association for the others.
+func (a Association) foreignKeyField(entityType reflect.Type) string {
+	if a.ForeignKeyField != "" {
+		return a.ForeignKeyField
+	}
+	if a.Polymorphic != "" {
+		return a.Polymorphic + "ID"
+	}
+	if a.Type == BelongTo {
+		return a.Name + "ID"
+	}
+	return entityType.Name() + "ID"
+}
+
+// referencesField returns the field referenced by the foreign key, of the association for belong-to,
+// and of the entity for the others.
+func (a Association) referencesField() string {
+	if a.References != "" {
+		return a.References
+	}
+	return "ID"
+}
+
+var associationTypes = map[string]AssociationType{
+	"belongs_to":   BelongTo,
+	"has_one":      HasOne,
+	"has_many":     HasMany,
+	"many_to_many": ManyToMany,
+}
+
+// parseAssocTag parses assoc tag such as `assoc:"belongs_to;fk:OwnerID;references:Code"`, `assoc:"has_many;polymorphic:Owner"`
+// or `assoc:"has_many;join"`. many_to_many takes no options, whose join table and keys are given by gorm tags such as
+// `gorm:"many2many:pet_sitters;joinForeignKey:SitterID"`.
+func parseAssocTag(entityType reflect.Type, field reflect.StructField) (association Association, ok bool) {
+	tag, ok := field.Tag.Lookup("assoc")
+	if !ok {
+		return association, false
+	}
+	for _, option := range strings.Split(tag, ";") {
+		key, value, _ := strings.Cut(strings.TrimSpace(option), ":")
+		switch key {
+		case "fk":
+			association.ForeignKeyField = value
+		case "references":
+			association.References = value
+		case "polymorphic":
+			association.Polymorphic = value
+		case "join":
+			association.JoinEntity = true
+		default:
+			associationType, ok := associationTypes[key]
+			if !ok {
+				panic(fmt.Sprintf("findAssociation: wrong assoc tag option '%s' of %s.%s", option, entityType.Name(), field.Name))
+			}
+			association.Type = associationType
+		}
+	}
+	if association.Type == 0 {
+		panic(fmt.Sprintf("findAssociation: assoc tag of %s.%s has no association type", entityType.Name(), field.Name))
+	}
+	if association.Type == ManyToMany && (association.ForeignKeyField != "" || association.References != "" || association.Polymorphic != "") {
+		panic(fmt.Sprintf("findAssociation: fk, references and polymorphic of %s.%s do not apply to many-to-many association, use gorm many2many tags", entityType.Name(), field.Name))
+	}
+	if association.JoinEntity && association.Type != HasMany {
+		panic(fmt.Sprintf("findAssociation: join entities of %s.%s are not has-many association", entityType.Name(), field.Name))
+	}
+	return association, true
 }
 
 var systemStructTypes = []any{
@@ -128,7 +219,6 @@ func toSnakeCase(camel string) string {
 }
 
 func findAssociations(ptrToEntity any) []Association {
-	var associations []Association
 	entityType := reflect.TypeOf(ptrToEntity)
 
 	if entityType.Kind() == reflect.Pointer || entityType.Kind() == reflect.Slice {
@@ -140,53 +230,7 @@ func findAssociations(ptrToEntity any) []Association {
 	if entityType.Kind() != reflect.Struct {
 		panic(fmt.Sprintf("findAssociation: entity[%s] is not struct type", entityType.String()))
 	}
-	numOfField := entityType.NumField()
-	for i := 0; i < numOfField; i++ {
-		field := entityType.Field(i)
-		if isSystemStructType(field.Type) {
-			continue
-		}
-
-		var association Association
-		association.Name = field.Name
-		association.FetchMode = ToFetchMode(field.Tag.Get("fetch"))
-
-		belongToForeignKey := fmt.Sprintf("%sID", field.Name)
-		hasForeignKey := fmt.Sprintf("%sID", entityType.Name())
-		if field.Type.Kind() == reflect.Struct {
-			association.PtrToEntity = reflect.New(field.Type).Interface()
-			if _, ok := entityType.FieldByName(belongToForeignKey); ok {
-				association.Type = BelongTo
-				association.ID = findIDValue(ptrToEntity, belongToForeignKey)
-			} else if _, ok := field.Type.FieldByName(hasForeignKey); ok {
-				association.Type = HasOne
-				association.ForeignKey = toSnakeCase(hasForeignKey)
-			}
-		} else if field.Type.Kind() == reflect.Pointer && field.Type.Elem().Kind() == reflect.Struct {
-			association.PtrToEntity = reflect.New(field.Type.Elem()).Interface()
-			if _, ok := entityType.FieldByName(belongToForeignKey); ok {
-				association.Type = BelongTo
-				association.ID = findIDValue(ptrToEntity, belongToForeignKey)
-			} else if _, ok := field.Type.Elem().FieldByName(hasForeignKey); ok {
-				association.Type = HasOne
-				association.ForeignKey = toSnakeCase(hasForeignKey)
-			}
-		} else if field.Type.Kind() == reflect.Slice && field.Type.Elem().Kind() == reflect.Struct {
-			association.PtrToEntity = reflect.New(field.Type).Interface()
-			if _, ok := field.Type.Elem().FieldByName(hasForeignKey); ok {
-				association.Type = HasMany
-				association.ForeignKey = toSnakeCase(hasForeignKey)
-			} else {
-				association.Type = ManyToMany
-				association.ForeignKey = toSnakeCase(hasForeignKey)
-			}
-		} else {
-			continue
-		}
-
-		associations = append(associations, association)
-	}
-	return associations
+	return metadataOf(entityType).associationsOf(ptrToEntity)
 }
 
 func ptrToEmptyElementOfPtrToSlice(ptrToSlice any) any {
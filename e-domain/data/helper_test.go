@@ -1,6 +1,7 @@
 package data
 
 import (
+	"fmt"
 	"github.com/sirupsen/logrus"
 	"github.com/stretchr/testify/assert"
 	"gorm.io/gorm"
@@ -309,6 +310,108 @@ func TestFindAssociations(t *testing.T) {
 			assert.Equal(t, expected, associations)
 		})
 	})
+	t.Run("assoc tag", func(t *testing.T) {
+		type Pet struct {
+			ID        uint
+			OwnerCode string
+		}
+		type Owner struct {
+			ID   uint
+			Code string
+			Pets []Pet `assoc:"has_many;fk:OwnerCode;references:Code"`
+		}
+		type Toy struct {
+			ID       uint
+			Holder   string
+			Owner    *Owner  `assoc:"belongs_to;fk:Holder;references:Code" fetch:"eager"`
+			Partners []Owner `assoc:"many_to_many"`
+		}
+
+		toy := Toy{ID: 1, Holder: "R-1"}
+		expected := []Association{
+			{
+				Name:            "Owner",
+				PtrToEntity:     &Owner{},
+				ID:              "R-1",
+				Type:            BelongTo,
+				FetchMode:       FetchEagerMode,
+				ForeignKeyField: "Holder",
+				References:      "Code",
+			},
+			{
+				Name:        "Partners",
+				PtrToEntity: new([]Owner),
+				Type:        ManyToMany,
+				FetchMode:   FetchLazyMode,
+			},
+		}
+		assert.Equal(t, expected, findAssociations(toy))
+
+		expected = []Association{
+			{
+				Name:            "Pets",
+				PtrToEntity:     new([]Pet),
+				ForeignKey:      "owner_code",
+				Type:            HasMany,
+				FetchMode:       FetchLazyMode,
+				ForeignKeyField: "OwnerCode",
+				References:      "Code",
+			},
+		}
+		assert.Equal(t, expected, findAssociations(Owner{}))
+
+		type Wrong struct {
+			ID    uint
+			Owner Owner `assoc:"belongs_to;fk:OwnerCode"`
+		}
+		assert.PanicsWithValue(t, "findAssociation: Wrong has no foreign key field OwnerCode of Owner", func() {
+			findAssociations(Wrong{})
+		})
+
+		type WrongManyToMany struct {
+			ID       uint
+			Partners []Owner `assoc:"many_to_many;fk:PartnerID"`
+		}
+		assert.PanicsWithValue(t, "findAssociation: fk, references and polymorphic of WrongManyToMany.Partners do not apply to many-to-many association, use gorm many2many tags", func() {
+			findAssociations(WrongManyToMany{})
+		})
+	})
+	t.Run("polymorphic", func(t *testing.T) {
+		type Comment struct {
+			ID        uint
+			OwnerID   uint
+			OwnerType string
+			Owner     fmt.Stringer
+		}
+		type Post struct {
+			ID       uint
+			Comments []Comment `gorm:"polymorphic:Owner"`
+		}
+
+		comment := Comment{ID: 1, OwnerID: 3, OwnerType: "unregistered"}
+		expected := []Association{
+			{
+				Name:        "Owner",
+				ID:          uint(3),
+				Type:        BelongTo,
+				FetchMode:   FetchLazyMode,
+				Polymorphic: "Owner",
+			},
+		}
+		assert.Equal(t, expected, findAssociations(comment))
+
+		expected = []Association{
+			{
+				Name:        "Comments",
+				PtrToEntity: new([]Comment),
+				ForeignKey:  "owner_id",
+				Type:        HasMany,
+				FetchMode:   FetchLazyMode,
+				Polymorphic: "Owner",
+			},
+		}
+		assert.Equal(t, expected, findAssociations(Post{}))
+	})
 }
 
 func TestFindLazyEntity(t *testing.T) {
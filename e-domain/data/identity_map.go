@@ -0,0 +1,74 @@
+package data
+
+import (
+	"context"
+	"reflect"
+	"sync"
+)
+
+// identityMap is the first-level cache of entities loaded in a transaction, keyed by entity type and ID.
+type identityMap struct {
+	m        sync.Mutex
+	entities map[entityKey]any // pointer to entity
+}
+
+type identityMapKey struct{}
+
+func identityMapFrom(ctx context.Context) (*identityMap, bool) {
+	resource, ok := transactionResource(ctx, identityMapKey{}, func() any {
+		return &identityMap{entities: make(map[entityKey]any)}
+	})
+	if !ok {
+		return nil, false
+	}
+	return resource.(*identityMap), true
+}
+
+// identityKey returns entityKey of an entity of entityType. A pointer id, e.g. *uint of a nullable foreign key, is dereferenced.
+func identityKey(entityType reflect.Type, id any) (entityKey, bool) {
+	if id == nil {
+		return entityKey{}, false
+	}
+	value := reflect.ValueOf(id)
+	if value.Kind() == reflect.Pointer {
+		if value.IsNil() {
+			return entityKey{}, false
+		}
+		value = value.Elem()
+	}
+	if value.IsZero() {
+		return entityKey{}, false
+	}
+	return entityKey{entityType: entityType, id: value.Interface()}, true
+}
+
+// get copies the cached entity of key to ptrToEntity.
+func (i *identityMap) get(key entityKey, ptrToEntity any) bool {
+	i.m.Lock()
+	defer i.m.Unlock()
+	cached, ok := i.entities[key]
+	if !ok {
+		return false
+	}
+	reflect.ValueOf(ptrToEntity).Elem().Set(reflect.ValueOf(cached).Elem())
+	return true
+}
+
+// put caches a copy of the entity unless the key is already cached.
+func (i *identityMap) put(key entityKey, ptrToEntity any) {
+	i.m.Lock()
+	defer i.m.Unlock()
+	if _, ok := i.entities[key]; ok {
+		return
+	}
+	value := reflect.ValueOf(ptrToEntity).Elem()
+	cached := reflect.New(value.Type())
+	cached.Elem().Set(value)
+	i.entities[key] = cached.Interface()
+}
+
+func (i *identityMap) remove(key entityKey) {
+	i.m.Lock()
+	defer i.m.Unlock()
+	delete(i.entities, key)
+}
@@ -0,0 +1,93 @@
+package data_test
+
+import (
+	"context"
+	"github.com/reuben-baek/go-learning/e-domain/data"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+	"strings"
+	"testing"
+)
+
+func TestIdentityMap(t *testing.T) {
+	type Company struct {
+		ID   uint
+		Name string
+	}
+	type Product struct {
+		data.LazyLoader `gorm:"-"`
+		ID              uint
+		Name            string
+		CompanyID       uint
+		Company         Company `gorm:"constraint:OnUpdate:CASCADE,OnDelete:SET NULL;"`
+	}
+
+	db := getGormDB()
+	db.AutoMigrate(&Company{}, &Product{})
+
+	companyQueries := 0
+	db.Callback().Query().After("gorm:query").Register("test:count_company_query", func(db *gorm.DB) {
+		if strings.Contains(db.Statement.SQL.String(), "FROM `companies`") {
+			companyQueries++
+		}
+	})
+
+	transactionManager := data.NewGormTransactionManager(db)
+	companyRepository := data.NewGormRepository[Company, uint](transactionManager)
+	productRepository := data.NewGormRepository[Product, uint](transactionManager)
+
+	ctx := context.Background()
+	kakao, _ := companyRepository.Create(ctx, Company{Name: "kakao"})
+	for _, name := range []string{"object-storage", "block-storage", "file-storage"} {
+		productRepository.Create(ctx, Product{Name: name, CompanyID: kakao.ID})
+	}
+
+	t.Run("lazy loads in transaction", func(t *testing.T) {
+		companyQueries = 0
+		err := transactionManager.Do(ctx, func(ctx context.Context) error {
+			products, err := productRepository.FindBy(ctx, "Company", kakao)
+			if err != nil {
+				return err
+			}
+			assert.Equal(t, 3, len(products))
+			for i := range products {
+				company, err := data.LazyLoadNow[Company]("Company", &products[i])
+				assert.Nil(t, err)
+				assert.Equal(t, kakao, company)
+			}
+			found, err := companyRepository.FindOne(ctx, kakao.ID)
+			assert.Equal(t, kakao, found)
+			return err
+		})
+		assert.Nil(t, err)
+		assert.Equal(t, 1, companyQueries)
+	})
+	t.Run("out of transaction", func(t *testing.T) {
+		companyQueries = 0
+		companyRepository.FindOne(ctx, kakao.ID)
+		companyRepository.FindOne(ctx, kakao.ID)
+		assert.Equal(t, 2, companyQueries)
+	})
+	t.Run("invalidate on update and delete", func(t *testing.T) {
+		err := transactionManager.Do(ctx, func(ctx context.Context) error {
+			found, _ := companyRepository.FindOne(ctx, kakao.ID)
+			found.Name = "kakao enterprise"
+			if _, err := companyRepository.Update(ctx, found); err != nil {
+				return err
+			}
+
+			companyQueries = 0
+			found, _ = companyRepository.FindOne(ctx, kakao.ID)
+			assert.Equal(t, "kakao enterprise", found.Name)
+			assert.Equal(t, 0, companyQueries)
+
+			if err := companyRepository.Delete(ctx, found); err != nil {
+				return err
+			}
+			_, err := companyRepository.FindOne(ctx, kakao.ID)
+			assert.Equal(t, data.NotFoundError, err)
+			return data.NotFoundError // rollback
+		})
+		assert.Equal(t, data.NotFoundError, err)
+	})
+}
@@ -0,0 +1,493 @@
+package data
+
+import (
+	"context"
+	"fmt"
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"gorm.io/gorm/schema"
+	"reflect"
+	"sort"
+	"sync"
+)
+
+// InheritanceStrategy is how subtypes of an inheritance hierarchy are stored.
+type InheritanceStrategy int
+
+const (
+	// SingleTable stores all subtypes in the table of the root struct, with columns of every subtype,
+	// distinguished by the discriminator column.
+	SingleTable InheritanceStrategy = iota + 1
+	// JoinedTable stores fields of the root struct in its table, and fields of each subtype in the table of the
+	// subtype, joined by the primary key. The root table has the discriminator column.
+	JoinedTable
+)
+
+// inheritance is a hierarchy of subtypes embedding the same root struct, which has the discriminator field.
+type inheritance struct {
+	strategy      InheritanceStrategy
+	base          reflect.Type // interface implemented by subtypes
+	root          reflect.Type
+	discriminator string
+	subtypes      map[string]reflect.Type
+	values        map[reflect.Type]string
+}
+
+var inheritances = struct {
+	m        sync.RWMutex
+	bases    map[reflect.Type]*inheritance
+	subtypes map[reflect.Type]*inheritance
+}{
+	bases:    make(map[reflect.Type]*inheritance),
+	subtypes: make(map[reflect.Type]*inheritance),
+}
+
+// RegisterInheritance registers subtypes of interface B by values of the discriminator field, stored by strategy.
+// Subtypes are structs embedding the same root struct, which has the discriminator field and the primary key.
+//
+//	type Product struct { ID uint; Kind string; Name string }
+//	type PhysicalProduct struct { Product; Weight float64 }
+//	data.RegisterInheritance[CatalogProduct](data.SingleTable, "Kind", map[string]any{"physical": PhysicalProduct{}})
+//
+// GormRepository of B returns the subtype of the discriminator value from FindOne and FindBy. Subtypes are mapped to
+// tables of the strategy by callbacks of RegisterInheritanceCallbacks, also in GormRepository of each subtype.
+func RegisterInheritance[B any](strategy InheritanceStrategy, discriminator string, subtypes map[string]any) {
+	baseType := reflect.TypeOf((*B)(nil)).Elem()
+	if baseType.Kind() != reflect.Interface {
+		panic(fmt.Sprintf("RegisterInheritance: base[%s] is not interface type", baseType))
+	}
+	h := &inheritance{
+		strategy:      strategy,
+		base:          baseType,
+		discriminator: discriminator,
+		subtypes:      make(map[string]reflect.Type),
+		values:        make(map[reflect.Type]string),
+	}
+	for value, subtype := range subtypes {
+		subtypeType := reflect.TypeOf(subtype)
+		if subtypeType.Kind() == reflect.Pointer {
+			subtypeType = subtypeType.Elem()
+		}
+		if subtypeType.Kind() != reflect.Struct {
+			panic(fmt.Sprintf("RegisterInheritance: subtype[%s] is not struct type", subtypeType))
+		}
+		if !subtypeType.Implements(baseType) && !reflect.PointerTo(subtypeType).Implements(baseType) {
+			panic(fmt.Sprintf("RegisterInheritance: subtype[%s] does not implement %s", subtypeType, baseType))
+		}
+		root, ok := rootOf(subtypeType, discriminator)
+		if !ok {
+			panic(fmt.Sprintf("RegisterInheritance: subtype[%s] embeds no struct of discriminator field %s", subtypeType, discriminator))
+		}
+		if h.root != nil && h.root != root {
+			panic(fmt.Sprintf("RegisterInheritance: subtype[%s] embeds %s, not %s", subtypeType, root, h.root))
+		}
+		h.root = root
+		h.subtypes[value] = subtypeType
+		h.values[subtypeType] = value
+	}
+
+	inheritances.m.Lock()
+	defer inheritances.m.Unlock()
+	inheritances.bases[baseType] = h
+	for subtypeType := range h.values {
+		inheritances.subtypes[subtypeType] = h
+	}
+}
+
+// rootOf returns the struct embedded in subtype, which has the discriminator field.
+func rootOf(subtype reflect.Type, discriminator string) (reflect.Type, bool) {
+	for i := 0; i < subtype.NumField(); i++ {
+		field := subtype.Field(i)
+		if !field.Anonymous || field.Type.Kind() != reflect.Struct {
+			continue
+		}
+		if _, ok := field.Type.FieldByName(discriminator); ok {
+			return field.Type, true
+		}
+	}
+	return nil, false
+}
+
+func inheritanceOfBase(baseType reflect.Type) (*inheritance, bool) {
+	inheritances.m.RLock()
+	defer inheritances.m.RUnlock()
+	h, ok := inheritances.bases[baseType]
+	return h, ok
+}
+
+func inheritanceOfSubtype(subtype reflect.Type) (*inheritance, bool) {
+	inheritances.m.RLock()
+	defer inheritances.m.RUnlock()
+	h, ok := inheritances.subtypes[subtype]
+	return h, ok
+}
+
+// sortedSubtypes returns subtypes in order of their discriminator values.
+func (h *inheritance) sortedSubtypes() []reflect.Type {
+	values := make([]string, 0, len(h.subtypes))
+	for value := range h.subtypes {
+		values = append(values, value)
+	}
+	sort.Strings(values)
+	subtypes := make([]reflect.Type, len(values))
+	for i, value := range values {
+		subtypes[i] = h.subtypes[value]
+	}
+	return subtypes
+}
+
+// isRootField reports whether field of a subtype schema is a field of the root struct.
+func (h *inheritance) isRootField(field *schema.Field) bool {
+	return len(field.BindNames) > 1 && field.BindNames[0] == h.root.Name()
+}
+
+// rootFieldNames returns names of fields of the root struct in subtypeSchema, including associations, excluding omits.
+func (h *inheritance) rootFieldNames(subtypeSchema *schema.Schema, omits []string) []string {
+	omitted := make(map[string]bool, len(omits))
+	for _, omit := range omits {
+		omitted[omit] = true
+	}
+	var names []string
+	for _, field := range subtypeSchema.Fields {
+		if _, ok := subtypeSchema.Relationships.Relations[field.Name]; ok && omitted[clause.Associations] {
+			continue
+		}
+		if h.isRootField(field) && !omitted[field.Name] && !omitted[field.DBName] {
+			names = append(names, field.Name)
+		}
+	}
+	return names
+}
+
+// findSubtype returns the subtype of the row of the root table of id.
+func (h *inheritance) findSubtype(db *gorm.DB, id any) (reflect.Type, error) {
+	rootSchema, err := parseSchema(db, reflect.New(h.root).Interface())
+	if err != nil {
+		return nil, err
+	}
+	if rootSchema.PrioritizedPrimaryField == nil {
+		return nil, fmt.Errorf("%s has no primary key", rootSchema.Name)
+	}
+	discriminator, err := lookUpColumn(rootSchema, h.discriminator)
+	if err != nil {
+		return nil, err
+	}
+	var values []string
+	if err := db.Table(rootSchema.Table).Where(clause.Eq{Column: clause.Column{Name: rootSchema.PrioritizedPrimaryField.DBName}, Value: id}).
+		Limit(1).Pluck(discriminator, &values).Error; err != nil {
+		return nil, err
+	}
+	if len(values) == 0 {
+		return nil, NotFoundError
+	}
+	subtype, ok := h.subtypes[values[0]]
+	if !ok {
+		return nil, fmt.Errorf("%s has no subtype of %s %s", h.base, h.discriminator, values[0])
+	}
+	return subtype, nil
+}
+
+// findOneOfInheritance finds the entity of id as the subtype of its discriminator value.
+func (u *GormRepository[T, ID]) findOneOfInheritance(ctx context.Context, h *inheritance, id ID) (T, error) {
+	var entity T
+	subtype, err := h.findSubtype(u.getReadGormDB(ctx), id)
+	if err != nil {
+		return entity, err
+	}
+	ptrToEntity := reflect.New(subtype).Interface()
+	if _, err := u.findOne(bindFetchPlan(ctx, subtype), ptrToEntity, id); err != nil {
+		return entity, err
+	}
+	return fromConcrete[T](ptrToEntity), nil
+}
+
+// findAllOfInheritance finds entities of every subtype by find, which returns a slice of the subtype.
+// Entities are grouped by subtypes, in order of their discriminator values.
+func findAllOfInheritance[T any](ctx context.Context, h *inheritance, find func(ctx context.Context, ptrToEntity any, ptrToSlice any) (any, error)) ([]T, error) {
+	var entities []T
+	for _, subtype := range h.sortedSubtypes() {
+		ptrToSlice := reflect.New(reflect.SliceOf(subtype))
+		found, err := find(bindFetchPlan(ctx, subtype), reflect.New(subtype).Interface(), ptrToSlice.Interface())
+		if err != nil {
+			return entities, err
+		}
+		values := reflect.ValueOf(found)
+		for i := 0; i < values.Len(); i++ {
+			entities = append(entities, fromConcrete[T](values.Index(i).Addr().Interface()))
+		}
+	}
+	return entities, nil
+}
+
+const inheritanceCallback = "data:inheritance"
+
+// RegisterInheritanceCallbacks registers callbacks of db mapping subtypes of RegisterInheritance to tables of their
+// strategy. Statements of subtypes with a table given by Table() are not mapped.
+//
+//	single-table: subtypes are read from and written to the root table, read filtering by the discriminator.
+//	joined-table: subtypes are read from the root table joined with the subtype table, and written to both.
+//
+// Both strategies set the discriminator field to the value of the subtype on create.
+func RegisterInheritanceCallbacks(db *gorm.DB) error {
+	if db.Callback().Query().Get(inheritanceCallback) != nil {
+		return nil
+	}
+	callbacks := []error{
+		db.Callback().Create().Before("gorm:create").Register(inheritanceCallback, inheritanceCreate),
+		db.Callback().Query().Before("gorm:query").Register(inheritanceCallback, inheritanceQuery),
+		db.Callback().Update().Before("gorm:update").Register(inheritanceCallback, inheritanceUpdate),
+		db.Callback().Delete().Before("gorm:delete").Register(inheritanceCallback, inheritanceDeleteBefore),
+		db.Callback().Delete().After("gorm:delete").Register(inheritanceCallback+"_root", inheritanceDeleteAfter),
+	}
+	for _, err := range callbacks {
+		if err != nil {
+			logrus.Errorf("RegisterInheritanceCallbacks: fail to register callback - %v", err)
+			return err
+		}
+	}
+	return nil
+}
+
+// inheritanceOfStatement returns inheritance and the root schema of the subtype of the statement.
+func inheritanceOfStatement(db *gorm.DB) (*inheritance, *schema.Schema, bool) {
+	stmt := db.Statement
+	if db.Error != nil || stmt.Schema == nil || stmt.Table != stmt.Schema.Table || stmt.TableExpr != nil {
+		return nil, nil, false
+	}
+	h, ok := inheritanceOfSubtype(stmt.Schema.ModelType)
+	if !ok {
+		return nil, nil, false
+	}
+	rootSchema, err := parseSchema(db, reflect.New(h.root).Interface())
+	if err != nil {
+		db.AddError(err)
+		return nil, nil, false
+	}
+	if rootSchema.PrioritizedPrimaryField == nil {
+		db.AddError(fmt.Errorf("%s has no primary key", rootSchema.Name))
+		return nil, nil, false
+	}
+	return h, rootSchema, true
+}
+
+// session returns a new session of db in the same transaction, for statements of the root table.
+func session(db *gorm.DB) *gorm.DB {
+	return db.Session(&gorm.Session{NewDB: true})
+}
+
+func inheritanceQuery(db *gorm.DB) {
+	h, rootSchema, ok := inheritanceOfStatement(db)
+	if !ok {
+		return
+	}
+	stmt := db.Statement
+	alias := clause.Table{Name: stmt.Table}
+	root := clause.Table{Name: rootSchema.Table}
+	switch h.strategy {
+	case SingleTable:
+		// select * from (select * from products where kind = 'physical') as physical_products
+		stmt.TableExpr = &clause.Expr{
+			SQL:  "(SELECT * FROM ? WHERE ? = ?) AS ?",
+			Vars: []any{root, clause.Column{Name: stmt.Schema.LookUpField(h.discriminator).DBName}, h.values[stmt.Schema.ModelType], alias},
+		}
+	case JoinedTable:
+		// select * from (select products.*, physical_products.weight from products
+		//   join physical_products on physical_products.id = products.id) as physical_products
+		sql := "(SELECT ?.*"
+		vars := []any{root}
+		for _, field := range stmt.Schema.Fields {
+			if field.DBName != "" && !h.isRootField(field) {
+				sql += ", ?"
+				vars = append(vars, clause.Column{Table: stmt.Table, Name: field.DBName})
+			}
+		}
+		primaryKey := rootSchema.PrioritizedPrimaryField.DBName
+		sql += " FROM ? JOIN ? ON ? = ?) AS ?"
+		vars = append(vars, root, alias, clause.Column{Table: stmt.Table, Name: primaryKey}, clause.Column{Table: rootSchema.Table, Name: primaryKey}, alias)
+		stmt.TableExpr = &clause.Expr{SQL: sql, Vars: vars}
+	}
+}
+
+func inheritanceCreate(db *gorm.DB) {
+	h, rootSchema, ok := inheritanceOfStatement(db)
+	if !ok {
+		return
+	}
+	stmt := db.Statement
+	discriminator := stmt.Schema.LookUpField(h.discriminator)
+	value := h.values[stmt.Schema.ModelType]
+	switch stmt.ReflectValue.Kind() {
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < stmt.ReflectValue.Len(); i++ {
+			db.AddError(discriminator.Set(stmt.Context, stmt.ReflectValue.Index(i), value))
+		}
+	case reflect.Struct:
+		db.AddError(discriminator.Set(stmt.Context, stmt.ReflectValue, value))
+	}
+
+	switch h.strategy {
+	case SingleTable:
+		stmt.Table = rootSchema.Table
+	case JoinedTable:
+		// the root row first, whose primary key is the primary key of the subtype row
+		if err := session(db).Table(rootSchema.Table).Select(h.rootFieldNames(stmt.Schema, stmt.Omits)).Create(stmt.Dest).Error; err != nil {
+			db.AddError(err)
+			return
+		}
+		stmt.Omits = append(stmt.Omits, h.rootFieldNames(stmt.Schema, primaryKeyNames(stmt.Schema))...)
+	}
+}
+
+func inheritanceUpdate(db *gorm.DB) {
+	h, rootSchema, ok := inheritanceOfStatement(db)
+	if !ok {
+		return
+	}
+	stmt := db.Statement
+	switch h.strategy {
+	case SingleTable:
+		stmt.Table = rootSchema.Table
+	case JoinedTable:
+		root := session(db).Table(rootSchema.Table).Model(stmt.Model)
+		if values, ok := stmt.Dest.(map[string]any); ok {
+			// changed columns of UnitOfWork
+			rootValues, subtypeValues := make(map[string]any), make(map[string]any)
+			for name, v := range values {
+				if field := stmt.Schema.LookUpField(name); field != nil && h.isRootField(field) {
+					rootValues[name] = v
+				} else {
+					subtypeValues[name] = v
+				}
+			}
+			if len(rootValues) > 0 {
+				db.AddError(root.Updates(rootValues).Error)
+			}
+			stmt.Dest = subtypeValues
+			return
+		}
+		omits := append(primaryKeyNames(stmt.Schema), stmt.Omits...)
+		if names := h.rootFieldNames(stmt.Schema, omits); len(names) > 0 {
+			db.AddError(root.Select(names).Updates(stmt.Dest).Error)
+		}
+		stmt.Omits = append(stmt.Omits, h.rootFieldNames(stmt.Schema, primaryKeyNames(stmt.Schema))...)
+	}
+}
+
+func inheritanceDeleteBefore(db *gorm.DB) {
+	h, rootSchema, ok := inheritanceOfStatement(db)
+	if ok && h.strategy == SingleTable {
+		db.Statement.Table = rootSchema.Table
+	}
+}
+
+func inheritanceDeleteAfter(db *gorm.DB) {
+	h, rootSchema, ok := inheritanceOfStatement(db)
+	if ok && h.strategy == JoinedTable {
+		// the root row after the subtype row
+		db.AddError(session(db).Table(rootSchema.Table).Delete(db.Statement.Dest).Error)
+	}
+}
+
+func primaryKeyNames(s *schema.Schema) []string {
+	names := make([]string, 0, len(s.PrimaryFields))
+	for _, field := range s.PrimaryFields {
+		names = append(names, field.Name)
+	}
+	return names
+}
+
+// AutoMigrateInheritance migrates tables of subtypes of B by the strategy of RegisterInheritance.
+// Tables of joined-table subtypes have the primary key and fields of the subtype.
+func AutoMigrateInheritance[B any](db *gorm.DB) error {
+	baseType := reflect.TypeOf((*B)(nil)).Elem()
+	h, ok := inheritanceOfBase(baseType)
+	if !ok {
+		return fmt.Errorf("AutoMigrateInheritance: %s is not registered", baseType)
+	}
+	rootSchema, err := parseSchema(db, reflect.New(h.root).Interface())
+	if err != nil {
+		return err
+	}
+	if err := db.AutoMigrate(reflect.New(h.root).Interface()); err != nil {
+		return err
+	}
+	for _, subtype := range h.sortedSubtypes() {
+		ptrToSubtype := reflect.New(subtype).Interface()
+		subtypeSchema, err := parseSchema(db, ptrToSubtype)
+		if err != nil {
+			return err
+		}
+		switch h.strategy {
+		case SingleTable:
+			// columns of the subtype are added to the root table. AutoMigrate with Table() of GORM
+			// names tables of associations by the table too.
+			migrator := db.Table(rootSchema.Table).Migrator()
+			for _, field := range subtypeSchema.Fields {
+				if field.DBName != "" && !migrator.HasColumn(ptrToSubtype, field.DBName) {
+					if err := migrator.AddColumn(ptrToSubtype, field.Name); err != nil {
+						return err
+					}
+				}
+			}
+		case JoinedTable:
+			if err := db.Table(subtypeSchema.Table).AutoMigrate(reflect.New(joinedTableOf(h, subtype)).Interface()); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// joinedTableOf returns a struct type of the subtype table, which has primary keys of the root and columns of subtype.
+func joinedTableOf(h *inheritance, subtype reflect.Type) reflect.Type {
+	var fields []reflect.StructField
+	for i := 0; i < h.root.NumField(); i++ {
+		field := h.root.Field(i)
+		_, primaryKey := schema.ParseTagSetting(field.Tag.Get("gorm"), ";")["PRIMARYKEY"]
+		if field.IsExported() && (field.Name == "ID" || primaryKey) {
+			field.Tag = `gorm:"primaryKey;autoIncrement:false"`
+			fields = append(fields, field)
+		}
+	}
+	metadata := metadataOf(subtype)
+	associations := make(map[string]bool)
+	for _, association := range metadata.associations {
+		associations[association.Name] = true
+	}
+	for i := 0; i < subtype.NumField(); i++ {
+		field := subtype.Field(i)
+		if field.Anonymous || !field.IsExported() || associations[field.Name] {
+			continue
+		}
+		fields = append(fields, field)
+	}
+	for i := range fields {
+		fields[i].Index = nil
+		fields[i].Offset = 0
+	}
+	return reflect.StructOf(fields)
+}
+
+// ptrToConcrete returns a pointer to the concrete entity of entity, which is entity itself unless T is an interface.
+func ptrToConcrete[T any](entity *T) any {
+	value := reflect.ValueOf(entity).Elem()
+	if value.Kind() != reflect.Interface {
+		return entity
+	}
+	concrete := value.Elem()
+	if concrete.Kind() == reflect.Pointer {
+		return concrete.Interface()
+	}
+	ptr := reflect.New(concrete.Type())
+	ptr.Elem().Set(concrete)
+	return ptr.Interface()
+}
+
+// fromConcrete returns the entity of ptrToEntity as T, which is the pointer if only the pointer implements T.
+func fromConcrete[T any](ptrToEntity any) T {
+	if entity, ok := reflect.ValueOf(ptrToEntity).Elem().Interface().(T); ok {
+		return entity
+	}
+	return ptrToEntity.(T)
+}
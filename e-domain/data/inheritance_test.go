@@ -0,0 +1,286 @@
+package data_test
+
+import (
+	"context"
+	"github.com/reuben-baek/go-learning/e-domain/data"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+type CatalogCompany struct {
+	ID   uint
+	Name string
+}
+
+// StockItem is the root of single-table inheritance, stored in stock_items with columns of every subtype.
+type StockItem struct {
+	data.LazyLoader  `gorm:"-"`
+	ID               uint
+	Kind             string
+	Name             string
+	CatalogCompanyID uint
+	CatalogCompany   CatalogCompany
+}
+
+type Item interface {
+	ItemName() string
+}
+
+type PhysicalItem struct {
+	StockItem
+	Weight int
+}
+
+func (p PhysicalItem) ItemName() string {
+	return "physical " + p.Name
+}
+
+type DigitalItem struct {
+	StockItem
+	DownloadURL string
+}
+
+func (d *DigitalItem) ItemName() string {
+	return "digital " + d.Name
+}
+
+// Listing is the root of joined-table inheritance, stored in listings, and subtypes in their tables.
+type Listing struct {
+	data.LazyLoader  `gorm:"-"`
+	ID               uint
+	Kind             string
+	Name             string
+	CatalogCompanyID uint
+	CatalogCompany   CatalogCompany
+}
+
+type CatalogListing interface {
+	ListingName() string
+}
+
+type PhysicalListing struct {
+	Listing
+	Weight int
+}
+
+func (p PhysicalListing) ListingName() string {
+	return "physical " + p.Name
+}
+
+type DigitalListing struct {
+	Listing
+	DownloadURL string
+}
+
+func (d *DigitalListing) ListingName() string {
+	return "digital " + d.Name
+}
+
+func init() {
+	data.RegisterInheritance[Item](data.SingleTable, "Kind", map[string]any{
+		"physical": PhysicalItem{},
+		"digital":  DigitalItem{},
+	})
+	data.RegisterInheritance[CatalogListing](data.JoinedTable, "Kind", map[string]any{
+		"physical": PhysicalListing{},
+		"digital":  DigitalListing{},
+	})
+}
+
+func TestGormRepository_SingleTableInheritance(t *testing.T) {
+	db := getGormDB()
+	assert.Nil(t, data.RegisterInheritanceCallbacks(db))
+	db.AutoMigrate(&CatalogCompany{})
+	assert.Nil(t, data.AutoMigrateInheritance[Item](db))
+
+	transactionManager := data.NewGormTransactionManager(db)
+	itemRepository := data.NewGormRepository[Item, uint](transactionManager)
+	physicalItemRepository := data.NewGormRepository[PhysicalItem, uint](transactionManager)
+
+	kakao := CatalogCompany{Name: "kakao"}
+	db.Create(&kakao)
+
+	ctx := context.Background()
+	var ssd PhysicalItem
+	var manual *DigitalItem
+	t.Run("create", func(t *testing.T) {
+		err := transactionManager.Do(ctx, func(ctx context.Context) error {
+			created, err := itemRepository.Create(ctx, PhysicalItem{StockItem: StockItem{Name: "ssd", CatalogCompanyID: kakao.ID}, Weight: 100})
+			assert.Nil(t, err)
+			ssd = created.(PhysicalItem)
+			created, err = itemRepository.Create(ctx, &DigitalItem{StockItem: StockItem{Name: "manual", CatalogCompanyID: kakao.ID}, DownloadURL: "https://kakao.com/manual"})
+			assert.Nil(t, err)
+			manual = created.(*DigitalItem)
+			return err
+		})
+		assert.Nil(t, err)
+		assert.Equal(t, "physical", ssd.Kind)
+		assert.Equal(t, "digital", manual.Kind)
+
+		var kinds []string
+		db.Table("stock_items").Order("id").Pluck("kind", &kinds)
+		assert.Equal(t, []string{"physical", "digital"}, kinds)
+	})
+	t.Run("find one returns the subtype", func(t *testing.T) {
+		err := transactionManager.Do(ctx, func(ctx context.Context) error {
+			found, err := itemRepository.FindOne(ctx, ssd.ID)
+			assert.Nil(t, err)
+			assert.Equal(t, "physical ssd", found.ItemName())
+			physical := found.(PhysicalItem)
+			assert.Equal(t, 100, physical.Weight)
+			company, err := data.LazyLoadNow[CatalogCompany]("CatalogCompany", &physical)
+			assert.Nil(t, err)
+			assert.Equal(t, kakao.Name, company.Name)
+
+			found, err = itemRepository.FindOne(ctx, manual.ID)
+			assert.Nil(t, err)
+			assert.Equal(t, "https://kakao.com/manual", found.(*DigitalItem).DownloadURL)
+
+			_, err = physicalItemRepository.FindOne(ctx, manual.ID)
+			assert.ErrorIs(t, err, data.NotFoundError)
+			_, err = itemRepository.FindOne(ctx, 100)
+			assert.ErrorIs(t, err, data.NotFoundError)
+			return nil
+		})
+		assert.Nil(t, err)
+	})
+	t.Run("find by returns subtypes", func(t *testing.T) {
+		err := transactionManager.Do(ctx, func(ctx context.Context) error {
+			items, err := itemRepository.FindBy(ctx, "CatalogCompany", kakao)
+			assert.Nil(t, err)
+			assert.Equal(t, 2, len(items))
+			assert.Equal(t, "digital manual", items[0].ItemName())
+			assert.Equal(t, "physical ssd", items[1].ItemName())
+
+			physicalItems, err := physicalItemRepository.FindBy(ctx, "CatalogCompany", kakao)
+			assert.Nil(t, err)
+			assert.Equal(t, 1, len(physicalItems))
+			assert.Equal(t, ssd.ID, physicalItems[0].ID)
+			return nil
+		})
+		assert.Nil(t, err)
+	})
+	t.Run("update and delete", func(t *testing.T) {
+		err := transactionManager.Do(ctx, func(ctx context.Context) error {
+			ssd.Weight = 200
+			updated, err := itemRepository.Update(ctx, ssd)
+			assert.Nil(t, err)
+			assert.Equal(t, 200, updated.(PhysicalItem).Weight)
+			return itemRepository.Delete(ctx, manual)
+		})
+		assert.Nil(t, err)
+
+		var count int64
+		db.Table("stock_items").Count(&count)
+		assert.Equal(t, int64(1), count)
+	})
+}
+
+func TestGormRepository_JoinedTableInheritance(t *testing.T) {
+	db := getGormDB()
+	assert.Nil(t, data.RegisterInheritanceCallbacks(db))
+	db.AutoMigrate(&CatalogCompany{})
+	assert.Nil(t, data.AutoMigrateInheritance[CatalogListing](db))
+
+	transactionManager := data.NewGormTransactionManager(db)
+	listingRepository := data.NewGormRepository[CatalogListing, uint](transactionManager)
+	physicalListingRepository := data.NewGormRepository[PhysicalListing, uint](transactionManager)
+
+	kakao := CatalogCompany{Name: "kakao"}
+	db.Create(&kakao)
+
+	ctx := context.Background()
+	var ssd PhysicalListing
+	var manual *DigitalListing
+	t.Run("create", func(t *testing.T) {
+		err := transactionManager.Do(ctx, func(ctx context.Context) error {
+			created, err := listingRepository.Create(ctx, PhysicalListing{Listing: Listing{Name: "ssd", CatalogCompanyID: kakao.ID}, Weight: 100})
+			assert.Nil(t, err)
+			ssd = created.(PhysicalListing)
+			created, err = listingRepository.Create(ctx, &DigitalListing{Listing: Listing{Name: "manual", CatalogCompanyID: kakao.ID}, DownloadURL: "https://kakao.com/manual"})
+			assert.Nil(t, err)
+			manual = created.(*DigitalListing)
+			return err
+		})
+		assert.Nil(t, err)
+
+		var kinds []string
+		db.Table("listings").Order("id").Pluck("kind", &kinds)
+		assert.Equal(t, []string{"physical", "digital"}, kinds)
+		var physical map[string]any
+		db.Table("physical_listings").Take(&physical)
+		assert.Equal(t, map[string]any{"id": int64(ssd.ID), "weight": int64(100)}, physical)
+	})
+	t.Run("find one returns the subtype", func(t *testing.T) {
+		err := transactionManager.Do(ctx, func(ctx context.Context) error {
+			found, err := listingRepository.FindOne(ctx, ssd.ID)
+			assert.Nil(t, err)
+			physical := found.(PhysicalListing)
+			assert.Equal(t, "ssd", physical.Name)
+			assert.Equal(t, 100, physical.Weight)
+			company, err := data.LazyLoadNow[CatalogCompany]("CatalogCompany", &physical)
+			assert.Nil(t, err)
+			assert.Equal(t, kakao.Name, company.Name)
+
+			found, err = listingRepository.FindOne(ctx, manual.ID)
+			assert.Nil(t, err)
+			assert.Equal(t, "digital manual", found.ListingName())
+			assert.Equal(t, "https://kakao.com/manual", found.(*DigitalListing).DownloadURL)
+
+			_, err = physicalListingRepository.FindOne(ctx, manual.ID)
+			assert.ErrorIs(t, err, data.NotFoundError)
+			return nil
+		})
+		assert.Nil(t, err)
+	})
+	t.Run("find by returns subtypes", func(t *testing.T) {
+		err := transactionManager.Do(ctx, func(ctx context.Context) error {
+			listings, err := listingRepository.FindBy(ctx, "CatalogCompany", kakao)
+			assert.Nil(t, err)
+			assert.Equal(t, 2, len(listings))
+			assert.Equal(t, "digital manual", listings[0].ListingName())
+			assert.Equal(t, "physical ssd", listings[1].ListingName())
+			return nil
+		})
+		assert.Nil(t, err)
+	})
+	t.Run("update writes both tables", func(t *testing.T) {
+		err := transactionManager.Do(ctx, func(ctx context.Context) error {
+			ssd.Name = "nvme"
+			ssd.Weight = 50
+			_, err := listingRepository.Update(ctx, ssd)
+			return err
+		})
+		assert.Nil(t, err)
+
+		err = transactionManager.Do(ctx, func(ctx context.Context) error {
+			ctx, _ = data.WithUnitOfWork(ctx)
+			found, err := physicalListingRepository.FindOne(ctx, ssd.ID)
+			assert.Nil(t, err)
+			assert.Equal(t, "nvme", found.Name)
+			assert.Equal(t, 50, found.Weight)
+			found.Name = "hdd"
+			found.Weight = 500
+			_, err = physicalListingRepository.Update(ctx, found)
+			return err
+		})
+		assert.Nil(t, err)
+
+		found, err := physicalListingRepository.FindOne(ctx, ssd.ID)
+		assert.Nil(t, err)
+		assert.Equal(t, "hdd", found.Name)
+		assert.Equal(t, 500, found.Weight)
+	})
+	t.Run("delete removes both rows", func(t *testing.T) {
+		err := transactionManager.Do(ctx, func(ctx context.Context) error {
+			return listingRepository.Delete(ctx, manual)
+		})
+		assert.Nil(t, err)
+
+		var count int64
+		db.Table("listings").Count(&count)
+		assert.Equal(t, int64(1), count)
+		db.Table("digital_listings").Count(&count)
+		assert.Equal(t, int64(0), count)
+	})
+}
@@ -2,24 +2,131 @@ package data
 
 import (
 	"context"
+	"fmt"
 	"github.com/sirupsen/logrus"
+	"reflect"
+	"strings"
 )
 
 type InMemoryRepository[T any, ID comparable] struct {
 	database           map[ID]T
+	sequences          map[reflect.Type]uint64 // last generated IDs by entity type
 	transactionManager TransactionManager
 }
 
 func NewInMemoryRepository[T any, ID comparable](transactionManager TransactionManager) *InMemoryRepository[T, ID] {
 	return &InMemoryRepository[T, ID]{
 		database:           make(map[ID]T),
+		sequences:          make(map[reflect.Type]uint64),
 		transactionManager: transactionManager,
 	}
 }
 
+// FindBy finds entities associated with byEntity by the association named name, or name+"s", in no particular order.
+// Belong-to associations are matched by the foreign key, and the others by IDs of entities in the association field.
+// name may be a dotted path of associations, such as "Departments.Company", which follows entities set in the
+// association fields to the last association.
 func (u *InMemoryRepository[T, ID]) FindBy(ctx context.Context, name string, byEntity any) ([]T, error) {
-	//TODO implement me
-	panic("implement me")
+	var entity T
+	if _, zero := findID[any, any](byEntity); zero {
+		panic(fmt.Sprintf("FindBy: %s's ID field is empty", name))
+	}
+	entityType := reflect.TypeOf(&entity).Elem()
+	if entityType.Kind() == reflect.Pointer {
+		entityType = entityType.Elem()
+	}
+	if isAssociationPath(name) {
+		return u.findByPath(entityType, name, byEntity)
+	}
+	metadata := metadataOf(entityType)
+	for _, association := range metadata.associations {
+		if association.Name != name && association.Name != name+"s" {
+			continue
+		}
+		var entities []T
+		for _, v := range u.database {
+			value := reflect.Indirect(reflect.ValueOf(v))
+			if value.IsValid() && association.associates(metadata, value, byEntity) {
+				entities = append(entities, v)
+			}
+		}
+		return entities, nil
+	}
+	return nil, fmt.Errorf("%T has no association with %T", entity, byEntity)
+}
+
+func (u *InMemoryRepository[T, ID]) findByPath(entityType reflect.Type, path string, byEntity any) ([]T, error) {
+	names := strings.Split(path, ".")
+	hops := make([]associationMetadata, len(names))
+	hopType := entityType
+	for i, name := range names {
+		if hopType.Kind() != reflect.Struct {
+			return nil, fmt.Errorf("%s of path %s is not struct type", hopType, path)
+		}
+		association, ok := pathAssociation(hopType, name)
+		if !ok {
+			return nil, fmt.Errorf("%s has no association %s of path %s", hopType.Name(), name, path)
+		}
+		hops[i] = association
+		hopType = association.entityType
+		if hopType.Kind() == reflect.Slice {
+			hopType = hopType.Elem()
+		}
+	}
+
+	var entities []T
+	for _, v := range u.database {
+		value := reflect.Indirect(reflect.ValueOf(v))
+		if value.IsValid() && associatesByPath(value, hops, byEntity) {
+			entities = append(entities, v)
+		}
+	}
+	return entities, nil
+}
+
+// associatesByPath reports whether entityValue refers to byEntity by the last of hops, following entities set in
+// association fields of the others.
+func associatesByPath(entityValue reflect.Value, hops []associationMetadata, byEntity any) bool {
+	metadata := metadataOf(entityValue.Type())
+	if len(hops) == 1 {
+		return hops[0].associates(metadata, entityValue, byEntity)
+	}
+	field, ok := metadata.field(entityValue, hops[0].Name)
+	if !ok {
+		return false
+	}
+	var values []reflect.Value
+	if field.Kind() == reflect.Slice {
+		for i := 0; i < field.Len(); i++ {
+			values = append(values, field.Index(i))
+		}
+	} else {
+		values = append(values, field)
+	}
+	for _, value := range values {
+		for value.Kind() == reflect.Pointer || value.Kind() == reflect.Interface {
+			value = value.Elem()
+		}
+		if value.IsValid() && value.Kind() == reflect.Struct && associatesByPath(value, hops[1:], byEntity) {
+			return true
+		}
+	}
+	return false
+}
+
+// FindByValue finds entities whose value object field named name equals value, in no particular order.
+func (u *InMemoryRepository[T, ID]) FindByValue(ctx context.Context, name string, value any) ([]T, error) {
+	var entity T
+	if _, err := valueObjectOf(&entity, name, value); err != nil {
+		return nil, err
+	}
+	var entities []T
+	for _, v := range u.database {
+		if equalValueObject(reflect.ValueOf(v), name, value) {
+			entities = append(entities, v)
+		}
+	}
+	return entities, nil
 }
 
 func (u *InMemoryRepository[T, ID]) FindOne(ctx context.Context, id ID) (T, error) {
@@ -32,14 +139,84 @@ func (u *InMemoryRepository[T, ID]) FindOne(ctx context.Context, id ID) (T, erro
 	}
 }
 
+// Create stores entity, creating new entities of its aggregate graph as GormRepository does. IDs of integer kinds are
+// generated for new entities, and foreign keys are set to them. Only entity is stored, and the others are in its graph.
 func (u *InMemoryRepository[T, ID]) Create(ctx context.Context, entity T) (T, error) {
 	transaction := u.transactionManager.Get(ctx)
 	logrus.Infof("InMemoryRepository.Create: transaction [%v] entity [%+v]", transaction, entity)
+	ptrToEntity := ptrToConcrete(&entity)
+	if value := reflect.ValueOf(ptrToEntity).Elem(); value.Kind() == reflect.Pointer && !value.IsNil() {
+		ptrToEntity = value.Interface()
+	}
+	if _, err := createGraph(inMemoryGraphStore[T, ID]{repository: u}, ptrToEntity); err != nil {
+		return entity, err
+	}
+	entity = fromConcrete[T](ptrToEntity)
 	id, _ := findID[T, ID](entity)
 	u.database[id] = entity
 	return entity, nil
 }
 
+// inMemoryGraphStore generates IDs of integer kinds for new entities of an aggregate graph, by a sequence of each entity
+// type skipping IDs in the repository. Entities of the other kinds, such as string IDs, should be created with IDs.
+type inMemoryGraphStore[T any, ID comparable] struct {
+	repository *InMemoryRepository[T, ID]
+}
+
+func (s inMemoryGraphStore[T, ID]) insert(ptrToEntity any) error {
+	entityValue := reflect.ValueOf(ptrToEntity).Elem()
+	field, ok := metadataOf(entityValue.Type()).field(entityValue, "ID")
+	if !ok || !field.IsZero() {
+		return nil
+	}
+	id := reflect.New(field.Type()).Elem()
+	for {
+		s.repository.sequences[entityValue.Type()]++
+		next := s.repository.sequences[entityValue.Type()]
+		switch field.Kind() {
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			id.SetInt(int64(next))
+		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+			id.SetUint(next)
+		default:
+			return nil
+		}
+		if key, ok := id.Interface().(ID); ok && s.stores(entityValue.Type()) {
+			if _, used := s.repository.database[key]; used {
+				continue
+			}
+		}
+		field.Set(id)
+		return nil
+	}
+}
+
+// stores reports whether entities of entityType are stored in the repository, which are T or its subtypes.
+func (s inMemoryGraphStore[T, ID]) stores(entityType reflect.Type) bool {
+	storedType := reflect.TypeOf((*T)(nil)).Elem()
+	switch storedType.Kind() {
+	case reflect.Interface:
+		return entityType.Implements(storedType) || reflect.PointerTo(entityType).Implements(storedType)
+	case reflect.Pointer:
+		return entityType == storedType.Elem()
+	}
+	return entityType == storedType
+}
+
+func (s inMemoryGraphStore[T, ID]) polymorphicValue(ptrToEntity any, association associationMetadata) (string, error) {
+	entityType := reflect.TypeOf(ptrToEntity).Elem()
+	value, ok := polymorphicValueOf(entityType)
+	if !ok {
+		return "", fmt.Errorf("%s is not registered polymorphic type", entityType.Name())
+	}
+	return value, nil
+}
+
+// link has nothing to store, as foreign keys are set in the graph.
+func (s inMemoryGraphStore[T, ID]) link(ptrToEntity any, association associationMetadata, ptrToElements []any) error {
+	return nil
+}
+
 func (u *InMemoryRepository[T, ID]) Update(ctx context.Context, entity T) (T, error) {
 	var v T
 	id, _ := findID[T, ID](entity)
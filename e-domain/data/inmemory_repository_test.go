@@ -58,3 +58,52 @@ func TestUserRepository(t *testing.T) {
 		return nil
 	})
 }
+
+func TestInMemoryRepository_FindBy(t *testing.T) {
+	type Team struct {
+		ID   string
+		Name string
+	}
+	type Skill struct {
+		ID   uint
+		Name string
+	}
+	type Member struct {
+		ID     string
+		TeamID string
+		Team   Team
+		Skills []Skill
+	}
+
+	transactionManager := data.NewDummyTransactionManager()
+	repository := data.NewInMemoryRepository[Member, string](transactionManager)
+
+	platform := Team{ID: "platform"}
+	golang := Skill{ID: 1, Name: "go"}
+	rust := Skill{ID: 2, Name: "rust"}
+	ctx := context.Background()
+	transactionManager.Do(ctx, func(ctx context.Context) error {
+		repository.Create(ctx, Member{ID: "reuben", TeamID: "platform", Skills: []Skill{golang, rust}})
+		repository.Create(ctx, Member{ID: "dennis", TeamID: "platform", Skills: []Skill{rust}})
+		repository.Create(ctx, Member{ID: "jane", TeamID: "mobile", Skills: []Skill{golang}})
+
+		ids := func(members []Member) []string {
+			var ids []string
+			for _, member := range members {
+				ids = append(ids, member.ID)
+			}
+			return ids
+		}
+		members, err := repository.FindBy(ctx, "Team", platform)
+		assert.Nil(t, err)
+		assert.ElementsMatch(t, []string{"reuben", "dennis"}, ids(members))
+
+		members, err = repository.FindBy(ctx, "Skill", golang)
+		assert.Nil(t, err)
+		assert.ElementsMatch(t, []string{"reuben", "jane"}, ids(members))
+
+		_, err = repository.FindBy(ctx, "Company", platform)
+		assert.NotNil(t, err)
+		return nil
+	})
+}
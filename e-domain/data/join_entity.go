@@ -0,0 +1,69 @@
+package data
+
+import (
+	"context"
+	"fmt"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"reflect"
+	"strings"
+)
+
+// mergeJoinEntities merges join entities of association with values, without replacing the whole collection.
+// Join entities are identified by their primary keys. New ones are created, existing ones are updated,
+// and ones missing in values are deleted, as join entities do not exist without the owner.
+func (u *GormRepository[T, ID]) mergeJoinEntities(ctx context.Context, db *gorm.DB, ptrToEntity any, association Association, values reflect.Value) error {
+	association.PtrToEntity = reflect.New(values.Type()).Interface()
+	mapping, err := resolveAssociation(db, ptrToEntity, association)
+	if err != nil {
+		return err
+	}
+	elementType := values.Type().Elem()
+	joinSchema, err := parseSchema(db, reflect.New(elementType).Interface())
+	if err != nil {
+		return err
+	}
+	ownerKey := findIDValue(ptrToEntity, mapping.keyField)
+	foreignKey := joinSchema.LookUpField(mapping.associationKeyField)
+	primaryKeyOf := func(value reflect.Value) string {
+		var b strings.Builder
+		for _, field := range joinSchema.PrimaryFields {
+			v, _ := field.ValueOf(ctx, value)
+			fmt.Fprintf(&b, "%v|", reflect.Indirect(reflect.ValueOf(v)))
+		}
+		return b.String()
+	}
+
+	existing := reflect.New(reflect.SliceOf(elementType))
+	if err := db.Omit(clause.Associations).Find(existing.Interface(), map[string]any{mapping.associationKey: ownerKey}).Error; err != nil {
+		return err
+	}
+	existingKeys := make(map[string]reflect.Value)
+	for i := 0; i < existing.Elem().Len(); i++ {
+		existingKeys[primaryKeyOf(existing.Elem().Index(i))] = existing.Elem().Index(i).Addr()
+	}
+
+	for i := 0; i < values.Len(); i++ {
+		ptrToJoin := reflect.New(elementType)
+		ptrToJoin.Elem().Set(values.Index(i))
+		if err := foreignKey.Set(ctx, ptrToJoin.Elem(), ownerKey); err != nil {
+			return err
+		}
+		key := primaryKeyOf(ptrToJoin.Elem())
+		if _, ok := existingKeys[key]; ok {
+			delete(existingKeys, key)
+			err = db.Model(ptrToJoin.Interface()).Select("*").Omit(clause.Associations).Updates(ptrToJoin.Interface()).Error
+		} else {
+			err = db.Omit(clause.Associations).Create(ptrToJoin.Interface()).Error
+		}
+		if err != nil {
+			return err
+		}
+	}
+	for _, ptrToJoin := range existingKeys {
+		if err := db.Delete(ptrToJoin.Interface()).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
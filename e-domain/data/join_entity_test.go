@@ -0,0 +1,144 @@
+package data_test
+
+import (
+	"context"
+	"github.com/reuben-baek/go-learning/e-domain/data"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+type JoinDepartment struct {
+	ID   uint
+	Name string
+}
+
+// JoinMembership is the join entity of JoinEmployee and JoinDepartment, with role and joined_at per membership.
+type JoinMembership struct {
+	data.LazyLoader  `gorm:"-"`
+	JoinEmployeeID   uint `gorm:"primaryKey"`
+	JoinDepartmentID uint `gorm:"primaryKey"`
+	JoinDepartment   JoinDepartment
+	Role             string
+	JoinedAt         time.Time
+}
+
+type JoinEmployee struct {
+	data.LazyLoader `gorm:"-"`
+	ID              uint
+	Name            string
+	Memberships     []JoinMembership `assoc:"has_many;join"`
+	// Departments is a view of Memberships, which manages the join table
+	Departments []JoinDepartment `gorm:"many2many:join_memberships" cascade:"none"`
+}
+
+func TestGormRepository_JoinEntity(t *testing.T) {
+	db := getGormDB()
+	assert.Nil(t, db.SetupJoinTable(&JoinEmployee{}, "Departments", &JoinMembership{}))
+	db.AutoMigrate(&JoinDepartment{}, &JoinEmployee{}, &JoinMembership{})
+
+	transactionManager := data.NewGormTransactionManager(db)
+	employeeRepository := data.NewGormRepository[JoinEmployee, uint](transactionManager)
+
+	storage := JoinDepartment{Name: "storage"}
+	compute := JoinDepartment{Name: "compute"}
+	network := JoinDepartment{Name: "network"}
+	db.Create(&storage)
+	db.Create(&compute)
+	db.Create(&network)
+	joinedAt := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+
+	roles := func(memberships []JoinMembership) map[uint]string {
+		roles := make(map[uint]string)
+		for _, membership := range memberships {
+			roles[membership.JoinDepartmentID] = membership.Role
+		}
+		return roles
+	}
+
+	ctx := context.Background()
+	var reuben JoinEmployee
+	t.Run("create", func(t *testing.T) {
+		err := transactionManager.Do(ctx, func(ctx context.Context) error {
+			var err error
+			reuben, err = employeeRepository.Create(ctx, JoinEmployee{
+				Name: "reuben",
+				Memberships: []JoinMembership{
+					{JoinDepartmentID: storage.ID, Role: "developer", JoinedAt: joinedAt},
+					{JoinDepartmentID: compute.ID, Role: "lead", JoinedAt: joinedAt},
+				},
+			})
+			return err
+		})
+		assert.Nil(t, err)
+	})
+	t.Run("load lazily and eagerly", func(t *testing.T) {
+		err := transactionManager.Do(ctx, func(ctx context.Context) error {
+			found, err := employeeRepository.FindOne(ctx, reuben.ID)
+			assert.Nil(t, err)
+			memberships, err := data.LazyLoadNow[[]JoinMembership]("Memberships", &found)
+			assert.Nil(t, err)
+			assert.Equal(t, map[uint]string{storage.ID: "developer", compute.ID: "lead"}, roles(memberships))
+			assert.True(t, joinedAt.Equal(memberships[0].JoinedAt))
+			department, err := data.LazyLoadNow[JoinDepartment]("JoinDepartment", &memberships[0])
+			assert.Nil(t, err)
+			assert.Equal(t, storage.Name, department.Name)
+
+			found, err = employeeRepository.FindOne(data.WithFetchPlan(ctx, "Memberships.JoinDepartment"), reuben.ID)
+			assert.Nil(t, err)
+			assert.Equal(t, 2, len(found.Memberships))
+			assert.Equal(t, compute.Name, found.Memberships[1].JoinDepartment.Name)
+			return nil
+		})
+		assert.Nil(t, err)
+	})
+	t.Run("find by", func(t *testing.T) {
+		err := transactionManager.Do(ctx, func(ctx context.Context) error {
+			employees, err := employeeRepository.FindBy(ctx, "Departments", compute)
+			assert.Nil(t, err)
+			assert.Equal(t, 1, len(employees))
+			assert.Equal(t, reuben.Name, employees[0].Name)
+
+			employees, err = employeeRepository.FindBy(ctx, "Departments", network)
+			assert.Nil(t, err)
+			assert.Equal(t, 0, len(employees))
+			return nil
+		})
+		assert.Nil(t, err)
+	})
+	t.Run("update adds, removes and modifies memberships", func(t *testing.T) {
+		err := transactionManager.Do(ctx, func(ctx context.Context) error {
+			found, err := employeeRepository.FindOne(ctx, reuben.ID)
+			assert.Nil(t, err)
+			memberships, err := data.LazyLoadNow[[]JoinMembership]("Memberships", &found)
+			assert.Nil(t, err)
+			for i := range memberships {
+				if memberships[i].JoinDepartmentID == storage.ID {
+					memberships[i].Role = "architect"
+				}
+			}
+			found.Memberships = append(memberships[:1], JoinMembership{JoinDepartmentID: network.ID, Role: "developer", JoinedAt: joinedAt})
+			_, err = employeeRepository.Update(ctx, found)
+			return err
+		})
+		assert.Nil(t, err)
+
+		var memberships []JoinMembership
+		db.Find(&memberships, "join_employee_id = ?", reuben.ID)
+		assert.Equal(t, map[uint]string{storage.ID: "architect", network.ID: "developer"}, roles(memberships))
+	})
+	t.Run("update keeps memberships not loaded", func(t *testing.T) {
+		err := transactionManager.Do(ctx, func(ctx context.Context) error {
+			found, err := employeeRepository.FindOne(ctx, reuben.ID)
+			assert.Nil(t, err)
+			found.Name = "reuben baek"
+			_, err = employeeRepository.Update(ctx, found)
+			return err
+		})
+		assert.Nil(t, err)
+
+		var count int64
+		db.Model(&JoinMembership{}).Where("join_employee_id = ?", reuben.ID).Count(&count)
+		assert.Equal(t, int64(2), count)
+	})
+}
@@ -1,6 +1,8 @@
 package data
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"github.com/sirupsen/logrus"
 	"reflect"
@@ -11,17 +13,91 @@ type Lazy[T any] interface {
 	Get() T
 }
 
+// LoadableLazy is Lazy which returns the error of its load, such as LazyLoad.
+type LoadableLazy[T any] interface {
+	Lazy[T]
+	Load() (T, error)
+}
+
+// LoadLazy returns the value of lazy and the error of its load if lazy is LoadableLazy, or Get() and nil otherwise.
+func LoadLazy[T any](lazy Lazy[T]) (T, error) {
+	if loadable, ok := lazy.(LoadableLazy[T]); ok {
+		return loadable.Load()
+	}
+	return lazy.Get(), nil
+}
+
+// MustGetLazy returns the value of lazy, and panics if its load failed.
+func MustGetLazy[T any](lazy Lazy[T]) T {
+	value, err := LoadLazy(lazy)
+	if err != nil {
+		panic(err)
+	}
+	return value
+}
+
 type LazyLoad[T any] struct {
-	m      sync.Mutex
-	done   bool
-	value  T
-	err    error
-	loadFn func() (any, error)
+	m            sync.Mutex
+	done         bool
+	value        T
+	err          error
+	retries      int // remaining loads allowed after a failure, or negative for unlimited
+	loadFn       func() (any, error)
+	referenceID  any
+	jsonPolicy   LazyJSONPolicy
+	jsonMaxDepth int
+	json         *lazyJSONState
 }
 
-func LazyLoadFn[T any](load func() (any, error)) *LazyLoad[T] {
-	return &LazyLoad[T]{loadFn: load}
+type lazyLoadConfig struct {
+	retries      int
+	referenceID  any
+	jsonPolicy   LazyJSONPolicy
+	jsonMaxDepth int
 }
+
+type LazyLoadOption func(c *lazyLoadConfig)
+
+// WithLazyLoadRetries limits retries of a failed load by later accesses to retries times, after which the error of
+// the last load is returned by all later accesses. Without it, a failed load is retried by every later access.
+func WithLazyLoadRetries(retries int) LazyLoadOption {
+	return func(c *lazyLoadConfig) {
+		c.retries = retries
+	}
+}
+
+func LazyLoadFn[T any](load func() (any, error), options ...LazyLoadOption) *LazyLoad[T] {
+	config := lazyLoadConfig{retries: -1}
+	for _, option := range options {
+		option(&config)
+	}
+	return &LazyLoad[T]{
+		loadFn:       load,
+		retries:      config.retries,
+		referenceID:  config.referenceID,
+		jsonPolicy:   config.jsonPolicy,
+		jsonMaxDepth: config.jsonMaxDepth,
+	}
+}
+
+// LazyLoadTo returns LazyLoad which loads the association name of lazyLoader by LazyLoadNow and converts it by to.
+// Errors of the load are returned by LazyLoad.Load instead of panics in load functions.
+// For belong-to associations, the foreign key is set as the reference ID emitted by LazyJSONReference.
+func LazyLoadTo[E any, T any](name string, lazyLoader LazyLoadable, to func(E) T, options ...LazyLoadOption) *LazyLoad[T] {
+	if _, ok := reflect.Indirect(reflect.ValueOf(lazyLoader)).Type().FieldByName(name + "ID"); ok {
+		if id := findIDValue(lazyLoader, name+"ID"); id != nil {
+			options = append([]LazyLoadOption{WithReferenceID(reflect.Indirect(reflect.ValueOf(id)).Interface())}, options...)
+		}
+	}
+	return LazyLoadFn[T](func() (any, error) {
+		entity, err := LazyLoadNow[E](name, lazyLoader)
+		if err != nil {
+			return nil, err
+		}
+		return to(entity), nil
+	}, options...)
+}
+
 func LazyLoadValue[T any](v T) *LazyLoad[T] {
 	return &LazyLoad[T]{
 		done:  true,
@@ -29,29 +105,88 @@ func LazyLoadValue[T any](v T) *LazyLoad[T] {
 	}
 }
 
+// Get returns the loaded value, or zero value if the load failed. Use Load to get the error.
 func (l *LazyLoad[T]) Get() T {
+	value, err := l.Load()
+	if err != nil {
+		logrus.Warnf("LazyLoad[%s].Get: %v", reflect.TypeOf(value), err)
+	}
+	return value
+}
+
+// MustGet returns the loaded value, and panics if the load failed.
+func (l *LazyLoad[T]) MustGet() T {
+	value, err := l.Load()
+	if err != nil {
+		panic(err)
+	}
+	return value
+}
+
+// Load loads the value at the first call, and returns the loaded value and the error of the load.
+// A failed load is retried by the next call, up to the retries of WithLazyLoadRetries.
+func (l *LazyLoad[T]) Load() (T, error) {
 	l.m.Lock()
 	defer l.m.Unlock()
 	if l.done {
-		return l.value
+		return l.value, l.err
 	}
 	if l.loadFn == nil {
 		l.done = true
-		return l.value
+		return l.value, nil
 	}
-	var value any
-	var ok bool
-	value, l.err = l.loadFn()
-	if l.err != nil {
-		l.done = true
-		return l.value
+	value, err := l.loadFn()
+	if err != nil {
+		var zero T
+		l.value, l.err = zero, err
+		if l.retries > 0 {
+			l.retries--
+		} else if l.retries == 0 {
+			l.done = true
+		}
+		return l.value, l.err
 	}
-	l.value, ok = value.(T)
+	loaded, ok := value.(T)
 	if !ok {
-		panic(fmt.Sprintf("LazyLoad got %s, not %s", reflect.TypeOf(value), reflect.TypeOf(l.value)))
+		var zero T
+		l.value, l.err, l.done = zero, fmt.Errorf("LazyLoad got %s, not %s", reflect.TypeOf(value), reflect.TypeOf(zero)), true
+		return l.value, l.err
 	}
-	l.done = true
-	return l.value
+	l.value, l.err, l.done = loaded, nil, true
+	return l.value, nil
+}
+
+// pending reports whether the value is not loaded yet.
+func (l *LazyLoad[T]) pending() bool {
+	l.m.Lock()
+	defer l.m.Unlock()
+	return !l.done
+}
+
+// LazyLoadScopeError is returned by a lazy load function called after its originating transaction or context has ended.
+type LazyLoadScopeError struct {
+	Entity string
+	Err    error
+}
+
+func (e *LazyLoadScopeError) Error() string {
+	return fmt.Sprintf("lazy load of %s after its transaction or context has ended: %v", e.Entity, e.Err)
+}
+
+func (e *LazyLoadScopeError) Unwrap() error {
+	return e.Err
+}
+
+// checkLazyLoadScope returns *LazyLoadScopeError if ctx, captured by a lazy load function, has ended.
+func checkLazyLoadScope(ctx context.Context, ptrToEntity any) error {
+	_, err := getTransactionSynchronization(ctx)
+	if !errors.Is(err, TransactionClosedError) {
+		err = ctx.Err()
+	}
+	if err != nil {
+		return &LazyLoadScopeError{Entity: reflect.TypeOf(ptrToEntity).Elem().String(), Err: err}
+	}
+	return nil
 }
 
 type LazyLoadable interface {
@@ -63,23 +198,54 @@ type LazyLoadable interface {
 	Entities() []string
 }
 
+// LazyLoader keeps load functions of lazy associations. It is safe for concurrent use once NewInstance is called.
+// Copies of an entity share the load functions.
 type LazyLoader struct {
+	m         *sync.RWMutex
 	loaderMap map[string]func() (any, error)
 }
 
 func (l *LazyLoader) NewInstance() {
+	l.m = &sync.RWMutex{}
 	l.loaderMap = make(map[string]func() (any, error))
 }
 
+// cloneLoadFuncs gives a copy of an entity its own load functions.
+func (l *LazyLoader) cloneLoadFuncs() {
+	if l.loaderMap == nil {
+		return
+	}
+	l.m.RLock()
+	loaderMap := make(map[string]func() (any, error), len(l.loaderMap))
+	for k, v := range l.loaderMap {
+		loaderMap[k] = v
+	}
+	l.m.RUnlock()
+	l.m = &sync.RWMutex{}
+	l.loaderMap = loaderMap
+}
+
 func (l *LazyLoader) SetLoadFunc(entity string, fn func() (any, error)) {
+	l.m.Lock()
+	defer l.m.Unlock()
 	l.loaderMap[entity] = fn
 }
 
 func (l *LazyLoader) DeleteLoadFunc(entity string) {
+	if l.m == nil {
+		return
+	}
+	l.m.Lock()
+	defer l.m.Unlock()
 	delete(l.loaderMap, entity)
 }
 
 func (l *LazyLoader) Entities() []string {
+	if l.m == nil {
+		return nil
+	}
+	l.m.RLock()
+	defer l.m.RUnlock()
 	entities := make([]string, 0, len(l.loaderMap))
 	for k, _ := range l.loaderMap {
 		entities = append(entities, k)
@@ -88,42 +254,71 @@ func (l *LazyLoader) Entities() []string {
 }
 
 func (l *LazyLoader) HasLoadFunc(entity string) bool {
+	if l.m == nil {
+		return false
+	}
+	l.m.RLock()
+	defer l.m.RUnlock()
 	_, ok := l.loaderMap[entity]
 	return ok
 }
 
+// Load calls the load function of name. The function runs without holding the lock,
+// so that load functions of different names can run concurrently.
 func (l *LazyLoader) Load(name string, emptyEntity any) (any, error) {
-	typeOf := reflect.TypeOf(emptyEntity)
-	if fn, ok := l.loaderMap[name]; ok {
+	var fn func() (any, error)
+	var ok bool
+	if l.m != nil {
+		l.m.RLock()
+		fn, ok = l.loaderMap[name]
+		l.m.RUnlock()
+	}
+	if ok {
 		loadedEntity, err := fn()
-		logrus.Debugf("LazyLoader.Load: LazyLoader[%p] loaded [%s] [%+v], err[%v]", l, typeOf.String(), loadedEntity, err)
-		delete(l.loaderMap, name)
+		logrus.Debugf("LazyLoader.Load: LazyLoader[%p] loaded [%T] [%+v], err[%v]", l, emptyEntity, loadedEntity, err)
+		if err == nil {
+			// a failed load function is kept to be retried
+			l.m.Lock()
+			delete(l.loaderMap, name)
+			l.m.Unlock()
+		}
 		return loadedEntity, err
 	} else {
-		return nil, fmt.Errorf("lazy load function for %s[%s] is not set", name, typeOf)
+		return nil, fmt.Errorf("lazy load function for %s[%T] is not set", name, emptyEntity)
 	}
 }
 
 func LazyLoadNow[T any](name string, lazyLoader LazyLoadable) (T, error) {
 	var entity T
-	var err error
-	var loaded any
-	loaded, err = lazyLoader.Load(name, entity)
-	if loaded == nil || err != nil {
+	child, loaded, err := loadField(name, lazyLoader, entity)
+	if !loaded || err != nil {
 		return entity, err
 	}
+	return child.Interface().(T), nil
+}
+
+// loadField loads the association name of lazyLoader, and sets it to the field of the same name.
+// It returns the field and false if the loaded value is nil.
+func loadField(name string, lazyLoader LazyLoadable, emptyEntity any) (reflect.Value, bool, error) {
+	loaded, err := lazyLoader.Load(name, emptyEntity)
+	if loaded == nil || err != nil {
+		return reflect.Value{}, false, err
+	}
 	valueOfParent := reflect.ValueOf(lazyLoader)
 	valueOfLoaded := reflect.ValueOf(loaded)
 
 	child := reflect.Indirect(valueOfParent).FieldByName(name)
+	logrus.Debugf("LazyLoadNow: parent[%s] field[%s %s] value[%s %s]", valueOfParent.Type().String(), name, child.Type().String(), valueOfLoaded.Type().String(), valueOfLoaded.Interface())
 	if child.Type().Kind() == reflect.Pointer {
-		logrus.Debugf("LazyLoadNow: parent[%s] field[%s %s] value[%s %s]", valueOfParent.Type().String(), name, child.Type().String(), valueOfLoaded.Type().String(), valueOfLoaded.Interface())
 		child.Set(reflect.New(reflect.TypeOf(loaded)))
 		child.Elem().Set(valueOfLoaded)
-		return child.Interface().(T), err
+	} else if child.Type().Kind() == reflect.Interface && !valueOfLoaded.Type().AssignableTo(child.Type()) {
+		// polymorphic entity implementing the interface by pointer receivers
+		ptrToLoaded := reflect.New(valueOfLoaded.Type())
+		ptrToLoaded.Elem().Set(valueOfLoaded)
+		child.Set(ptrToLoaded)
 	} else {
-		logrus.Debugf("LazyLoadNow: parent[%s] field[%s %s] value[%s %s]", valueOfParent.Type().String(), name, child.Type().String(), valueOfLoaded.Type().String(), valueOfLoaded.Interface())
 		child.Set(reflect.Indirect(valueOfLoaded))
-		return reflect.Indirect(reflect.ValueOf(loaded)).Interface().(T), err
 	}
+	return child, true, nil
 }
@@ -0,0 +1,226 @@
+package data
+
+import (
+	"encoding/json"
+	"reflect"
+)
+
+// LazyJSONPolicy decides how LazyLoad is marshalled to JSON when it is not loaded.
+type LazyJSONPolicy int
+
+const (
+	// LazyJSONLoaded emits loaded values only, and null for unloaded ones.
+	LazyJSONLoaded LazyJSONPolicy = iota
+	// LazyJSONReference emits {"id": ...} for unloaded belong-to associations, and null for the others.
+	LazyJSONReference
+	// LazyJSONForceLoad loads unloaded values up to the max depth, and beyond it works as LazyJSONReference.
+	LazyJSONForceLoad
+)
+
+// lazyJSONState is the marshalling state given to LazyLoad by its parent.
+type lazyJSONState struct {
+	policy   LazyJSONPolicy
+	maxDepth int
+	depth    int
+	visiting map[entityKey]bool // entities on the path from the root, to detect cycles
+}
+
+func (s *lazyJSONState) child(key entityKey, hasKey bool) *lazyJSONState {
+	visiting := make(map[entityKey]bool, len(s.visiting)+1)
+	for k := range s.visiting {
+		visiting[k] = true
+	}
+	if hasKey {
+		visiting[key] = true
+	}
+	return &lazyJSONState{policy: s.policy, maxDepth: s.maxDepth, depth: s.depth + 1, visiting: visiting}
+}
+
+type lazyJSONNode interface {
+	// withJSONState returns a copy of the node marshalled by state.
+	withJSONState(state *lazyJSONState) any
+}
+
+var lazyJSONNodeType = reflect.TypeOf((*lazyJSONNode)(nil)).Elem()
+
+// WithJSONPolicy sets the policy of LazyLoad marshalled by json.Marshal, not by MarshalLazyJSON.
+func WithJSONPolicy(policy LazyJSONPolicy, maxDepth int) LazyLoadOption {
+	return func(c *lazyLoadConfig) {
+		c.jsonPolicy = policy
+		c.jsonMaxDepth = maxDepth
+	}
+}
+
+// WithReferenceID sets the ID emitted by LazyJSONReference while LazyLoad is not loaded.
+// LazyLoadTo sets it from the foreign key field of belong-to associations.
+func WithReferenceID(id any) LazyLoadOption {
+	return func(c *lazyLoadConfig) {
+		c.referenceID = id
+	}
+}
+
+// MarshalLazyJSON marshals v to JSON, and LazyLoad in v by policy.
+// Entities already on the path from v are emitted as {"id": ...} to break cycles such as Category.Parent.
+// The policy is given to copies of LazyLoad of v, so v may be marshalled concurrently, such as entities shared by
+// the identity map or the cache.
+func MarshalLazyJSON(v any, policy LazyJSONPolicy, maxDepth int) ([]byte, error) {
+	key, hasKey := jsonEntityKey(reflect.ValueOf(v))
+	root := &lazyJSONState{policy: policy, maxDepth: maxDepth, depth: -1}
+	return marshalWithLazyJSON(v, reflect.ValueOf(v), root.child(key, hasKey))
+}
+
+// marshalWithLazyJSON marshals a copy of v, in which LazyLoad found in valueOf are replaced by their copies with state,
+// so LazyLoad shared by other marshalling keeps its own policy.
+func marshalWithLazyJSON(v any, valueOf reflect.Value, state *lazyJSONState) ([]byte, error) {
+	if !valueOf.IsValid() {
+		return json.Marshal(v)
+	}
+	return json.Marshal(copyWithLazyJSON(valueOf, state, make(map[uintptr]reflect.Value)).Interface())
+}
+
+// withJSONState returns a copy of l with state, which loads by l. l itself is not changed.
+func (l *LazyLoad[T]) withJSONState(state *lazyJSONState) any {
+	l.m.Lock()
+	defer l.m.Unlock()
+	c := &LazyLoad[T]{done: l.done, value: l.value, err: l.err, referenceID: l.referenceID, json: state}
+	if !l.done {
+		c.loadFn = func() (any, error) {
+			return l.Load()
+		}
+	}
+	return c
+}
+
+func (l *LazyLoad[T]) MarshalJSON() ([]byte, error) {
+	l.m.Lock()
+	state := l.json
+	loaded := l.done && l.err == nil
+	value := l.value
+	referenceID := l.referenceID
+	l.m.Unlock()
+	if state == nil {
+		state = &lazyJSONState{policy: l.jsonPolicy, maxDepth: l.jsonMaxDepth}
+	}
+
+	if !loaded {
+		switch {
+		case state.policy == LazyJSONForceLoad && state.depth < state.maxDepth:
+			var err error
+			if value, err = l.Load(); err != nil {
+				return nil, err
+			}
+		case state.policy != LazyJSONLoaded && referenceID != nil:
+			return json.Marshal(map[string]any{"id": referenceID})
+		default:
+			return []byte("null"), nil
+		}
+	}
+
+	valueOf := reflect.ValueOf(&value).Elem()
+	key, hasKey := jsonEntityKey(valueOf)
+	if hasKey && state.visiting[key] {
+		return json.Marshal(map[string]any{"id": key.id})
+	}
+	return marshalWithLazyJSON(value, valueOf, state.child(key, hasKey))
+}
+
+// UnmarshalJSON sets the value loaded. An ID reference such as {"id": 3} sets the ID of the value only.
+func (l *LazyLoad[T]) UnmarshalJSON(data []byte) error {
+	var value T
+	if err := json.Unmarshal(data, &value); err != nil {
+		return err
+	}
+	l.m.Lock()
+	defer l.m.Unlock()
+	l.value, l.err, l.done = value, nil, true
+	return nil
+}
+
+// copyWithLazyJSON returns a copy of value, in which LazyLoad are replaced by their copies with state, without
+// descending into them. copied keeps copies of pointers, for shared and cyclic pointers.
+func copyWithLazyJSON(value reflect.Value, state *lazyJSONState, copied map[uintptr]reflect.Value) reflect.Value {
+	switch value.Kind() {
+	case reflect.Pointer:
+		if value.IsNil() {
+			return value
+		}
+		if node, ok := value.Interface().(lazyJSONNode); ok {
+			return reflect.ValueOf(node.withJSONState(state))
+		}
+		if c, ok := copied[value.Pointer()]; ok {
+			return c
+		}
+		c := reflect.New(value.Type().Elem())
+		copied[value.Pointer()] = c
+		c.Elem().Set(copyWithLazyJSON(value.Elem(), state, copied))
+		return c
+	case reflect.Interface:
+		if value.IsNil() {
+			return value
+		}
+		c := reflect.New(value.Type()).Elem()
+		c.Set(copyWithLazyJSON(value.Elem(), state, copied))
+		return c
+	case reflect.Struct:
+		if reflect.PointerTo(value.Type()).Implements(lazyJSONNodeType) {
+			node := value
+			if !node.CanAddr() {
+				node = reflect.New(value.Type()).Elem()
+				node.Set(value)
+			}
+			return reflect.ValueOf(node.Addr().Interface().(lazyJSONNode).withJSONState(state)).Elem()
+		}
+		c := reflect.New(value.Type()).Elem()
+		c.Set(value)
+		for i := 0; i < value.NumField(); i++ {
+			if value.Type().Field(i).IsExported() {
+				c.Field(i).Set(copyWithLazyJSON(value.Field(i), state, copied))
+			}
+		}
+		return c
+	case reflect.Slice:
+		if value.IsNil() {
+			return value
+		}
+		c := reflect.MakeSlice(value.Type(), value.Len(), value.Len())
+		for i := 0; i < value.Len(); i++ {
+			c.Index(i).Set(copyWithLazyJSON(value.Index(i), state, copied))
+		}
+		return c
+	case reflect.Array:
+		c := reflect.New(value.Type()).Elem()
+		for i := 0; i < value.Len(); i++ {
+			c.Index(i).Set(copyWithLazyJSON(value.Index(i), state, copied))
+		}
+		return c
+	case reflect.Map:
+		if value.IsNil() {
+			return value
+		}
+		c := reflect.MakeMapWithSize(value.Type(), value.Len())
+		iter := value.MapRange()
+		for iter.Next() {
+			c.SetMapIndex(iter.Key(), copyWithLazyJSON(iter.Value(), state, copied))
+		}
+		return c
+	}
+	return value
+}
+
+// jsonEntityKey returns the key of value, if it is an entity with non-zero ID.
+func jsonEntityKey(value reflect.Value) (entityKey, bool) {
+	for value.Kind() == reflect.Pointer || value.Kind() == reflect.Interface {
+		if value.IsNil() {
+			return entityKey{}, false
+		}
+		value = value.Elem()
+	}
+	if value.Kind() != reflect.Struct {
+		return entityKey{}, false
+	}
+	id := value.FieldByName("ID")
+	if !id.IsValid() || id.IsZero() || !id.Comparable() {
+		return entityKey{}, false
+	}
+	return entityKey{entityType: value.Type(), id: id.Interface()}, true
+}
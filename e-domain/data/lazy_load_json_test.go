@@ -0,0 +1,132 @@
+package data_test
+
+import (
+	"encoding/json"
+	"github.com/reuben-baek/go-learning/e-domain/data"
+	"github.com/stretchr/testify/assert"
+	"sync"
+	"testing"
+)
+
+type JSONCategory struct {
+	ID     uint
+	Name   string
+	Parent *data.LazyLoad[JSONCategory] `json:",omitempty"`
+}
+
+func TestLazyLoad_JSON(t *testing.T) {
+	loads := 0
+	var categories map[uint]JSONCategory
+	category := func(id uint, name string, parentID uint) JSONCategory {
+		c := JSONCategory{ID: id, Name: name}
+		if parentID != 0 {
+			c.Parent = data.LazyLoadFn[JSONCategory](func() (any, error) {
+				loads++
+				return categories[parentID], nil
+			}, data.WithReferenceID(parentID))
+		}
+		return c
+	}
+	newCategories := func() {
+		loads = 0
+		categories = map[uint]JSONCategory{
+			1: category(1, "cloud", 0),
+			2: category(2, "storage", 1),
+			3: category(3, "object-storage", 2),
+			// cycle
+			4: category(4, "chicken", 5),
+			5: category(5, "egg", 4),
+		}
+	}
+
+	t.Run("loaded only", func(t *testing.T) {
+		newCategories()
+		marshalled, err := json.Marshal(categories[3])
+		assert.Nil(t, err)
+		assert.JSONEq(t, `{"ID":3,"Name":"object-storage","Parent":null}`, string(marshalled))
+
+		categories[3].Parent.Get()
+		marshalled, err = data.MarshalLazyJSON(categories[3], data.LazyJSONLoaded, 0)
+		assert.Nil(t, err)
+		assert.JSONEq(t, `{"ID":3,"Name":"object-storage","Parent":{"ID":2,"Name":"storage","Parent":null}}`, string(marshalled))
+		assert.Equal(t, 1, loads)
+	})
+	t.Run("reference", func(t *testing.T) {
+		newCategories()
+		marshalled, err := data.MarshalLazyJSON(categories[3], data.LazyJSONReference, 0)
+		assert.Nil(t, err)
+		assert.JSONEq(t, `{"ID":3,"Name":"object-storage","Parent":{"id":2}}`, string(marshalled))
+		assert.Equal(t, 0, loads)
+	})
+	t.Run("force load to max depth", func(t *testing.T) {
+		newCategories()
+		marshalled, err := data.MarshalLazyJSON(categories[3], data.LazyJSONForceLoad, 1)
+		assert.Nil(t, err)
+		assert.JSONEq(t, `{"ID":3,"Name":"object-storage","Parent":{"ID":2,"Name":"storage","Parent":{"id":1}}}`, string(marshalled))
+		assert.Equal(t, 1, loads)
+
+		newCategories()
+		marshalled, err = data.MarshalLazyJSON(categories[3], data.LazyJSONForceLoad, 5)
+		assert.Nil(t, err)
+		assert.JSONEq(t, `{"ID":3,"Name":"object-storage","Parent":{"ID":2,"Name":"storage","Parent":{"ID":1,"Name":"cloud"}}}`, string(marshalled))
+		assert.Equal(t, 2, loads)
+	})
+	t.Run("cycle", func(t *testing.T) {
+		newCategories()
+		marshalled, err := data.MarshalLazyJSON(categories[4], data.LazyJSONForceLoad, 10)
+		assert.Nil(t, err)
+		assert.JSONEq(t, `{"ID":4,"Name":"chicken","Parent":{"ID":5,"Name":"egg","Parent":{"id":4}}}`, string(marshalled))
+	})
+	t.Run("json.Marshal after MarshalLazyJSON", func(t *testing.T) {
+		newCategories()
+		_, err := data.MarshalLazyJSON(categories[3], data.LazyJSONReference, 0)
+		assert.Nil(t, err)
+		marshalled, err := json.Marshal(categories[3])
+		assert.Nil(t, err)
+		assert.JSONEq(t, `{"ID":3,"Name":"object-storage","Parent":null}`, string(marshalled))
+
+		_, err = data.MarshalLazyJSON(categories[3], data.LazyJSONForceLoad, 1)
+		assert.Nil(t, err)
+		marshalled, err = json.Marshal(categories[2])
+		assert.Nil(t, err)
+		assert.JSONEq(t, `{"ID":2,"Name":"storage","Parent":null}`, string(marshalled))
+		assert.Equal(t, 1, loads)
+	})
+	t.Run("concurrent MarshalLazyJSON of a shared entity", func(t *testing.T) {
+		newCategories()
+		var wg sync.WaitGroup
+		for i := 0; i < 500; i++ {
+			wg.Add(2)
+			go func() {
+				defer wg.Done()
+				marshalled, err := data.MarshalLazyJSON(categories[3], data.LazyJSONReference, 0)
+				assert.Nil(t, err)
+				assert.JSONEq(t, `{"ID":3,"Name":"object-storage","Parent":{"id":2}}`, string(marshalled))
+			}()
+			go func() {
+				defer wg.Done()
+				marshalled, err := data.MarshalLazyJSON(categories[3], data.LazyJSONLoaded, 0)
+				assert.Nil(t, err)
+				assert.JSONEq(t, `{"ID":3,"Name":"object-storage","Parent":null}`, string(marshalled))
+			}()
+		}
+		wg.Wait()
+		assert.Equal(t, 0, loads)
+	})
+	t.Run("policy of LazyLoad", func(t *testing.T) {
+		lazy := data.LazyLoadFn[JSONCategory](func() (any, error) {
+			return JSONCategory{ID: 1, Name: "cloud"}, nil
+		}, data.WithJSONPolicy(data.LazyJSONForceLoad, 1))
+		marshalled, err := json.Marshal(lazy)
+		assert.Nil(t, err)
+		assert.JSONEq(t, `{"ID":1,"Name":"cloud"}`, string(marshalled))
+	})
+	t.Run("unmarshal", func(t *testing.T) {
+		var c JSONCategory
+		err := json.Unmarshal([]byte(`{"ID":3,"Name":"object-storage","Parent":{"id":2}}`), &c)
+		assert.Nil(t, err)
+		parent, err := c.Parent.Load()
+		assert.Nil(t, err)
+		assert.Equal(t, JSONCategory{ID: 2}, parent)
+	})
+}
@@ -2,6 +2,7 @@ package data
 
 import (
 	"context"
+	"errors"
 	"github.com/sirupsen/logrus"
 	"github.com/stretchr/testify/assert"
 	"testing"
@@ -41,3 +42,107 @@ func TestLazyLoad_in_Struct(t *testing.T) {
 	assert.Equal(t, kakao, company)
 
 }
+
+func TestLazyLoad_Load(t *testing.T) {
+	type Company struct {
+		ID   uint
+		Name string
+	}
+	kakao := Company{ID: 1, Name: "kakao"}
+	fail := errors.New("connection refused")
+
+	flaky := func(failures int) (func() (any, error), *int) {
+		calls := 0
+		return func() (any, error) {
+			calls++
+			if calls <= failures {
+				return nil, fail
+			}
+			return kakao, nil
+		}, &calls
+	}
+
+	t.Run("error is retried by default", func(t *testing.T) {
+		load, calls := flaky(2)
+		lazy := LazyLoadFn[Company](load)
+		company, err := lazy.Load()
+		assert.Equal(t, fail, err)
+		assert.Equal(t, Company{}, company)
+		assert.Equal(t, Company{}, lazy.Get())
+		assert.Equal(t, kakao, lazy.MustGet())
+		assert.Equal(t, kakao, lazy.Get())
+		assert.Equal(t, 3, *calls)
+	})
+	t.Run("retries", func(t *testing.T) {
+		load, calls := flaky(2)
+		lazy := LazyLoadFn[Company](load, WithLazyLoadRetries(2))
+		_, err := lazy.Load()
+		assert.Equal(t, fail, err)
+		_, err = lazy.Load()
+		assert.Equal(t, fail, err)
+		company, err := lazy.Load()
+		assert.Nil(t, err)
+		assert.Equal(t, kakao, company)
+		assert.Equal(t, kakao, lazy.MustGet())
+		assert.Equal(t, 3, *calls)
+	})
+	t.Run("error is kept after retries", func(t *testing.T) {
+		load, calls := flaky(2)
+		lazy := LazyLoadFn[Company](load, WithLazyLoadRetries(1))
+		_, err := lazy.Load()
+		assert.Equal(t, fail, err)
+		_, err = lazy.Load()
+		assert.Equal(t, fail, err)
+		_, err = lazy.Load()
+		assert.Equal(t, fail, err)
+		assert.PanicsWithError(t, fail.Error(), func() { lazy.MustGet() })
+		assert.Equal(t, 2, *calls)
+	})
+	t.Run("Lazy of other implementations", func(t *testing.T) {
+		var lazy Lazy[Company] = constantLazy[Company]{value: kakao}
+		company, err := LoadLazy(lazy)
+		assert.Nil(t, err)
+		assert.Equal(t, kakao, company)
+		assert.Equal(t, kakao, MustGetLazy(lazy))
+
+		load, _ := flaky(1)
+		lazy = LazyLoadFn[Company](load)
+		_, err = LoadLazy(lazy)
+		assert.Equal(t, fail, err)
+	})
+	t.Run("wrong type", func(t *testing.T) {
+		lazy := LazyLoadFn[Company](func() (any, error) { return "kakao", nil })
+		_, err := lazy.Load()
+		assert.EqualError(t, err, "LazyLoad got string, not data.Company")
+	})
+	t.Run("LazyLoadTo", func(t *testing.T) {
+		type User struct {
+			LazyLoader
+			CompanyID uint
+			Company   Company
+		}
+		load, _ := flaky(1)
+		user := User{CompanyID: kakao.ID}
+		user.NewInstance()
+		user.SetLoadFunc("Company", load)
+
+		lazy := LazyLoadTo("Company", &user, func(c Company) string { return c.Name }, WithLazyLoadRetries(1))
+		_, err := lazy.Load()
+		assert.Equal(t, fail, err)
+		assert.True(t, user.HasLoadFunc("Company"))
+		name, err := lazy.Load()
+		assert.Nil(t, err)
+		assert.Equal(t, "kakao", name)
+		assert.Equal(t, kakao, user.Company)
+		assert.False(t, user.HasLoadFunc("Company"))
+	})
+}
+
+// constantLazy is Lazy implemented outside of LazyLoad.
+type constantLazy[T any] struct {
+	value T
+}
+
+func (c constantLazy[T]) Get() T {
+	return c.value
+}
@@ -0,0 +1,74 @@
+package outbox
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/reuben-baek/go-learning/e-domain/data"
+	"gorm.io/gorm"
+	"time"
+)
+
+// Event is a message appended to the outbox table in the same transaction as repository writes.
+// Events of the same AggregateKey are delivered in the order they are appended.
+type Event struct {
+	ID            uint   `gorm:"primaryKey"`
+	AggregateType string `gorm:"size:255"`
+	AggregateKey  string `gorm:"size:255;index"`
+	Type          string `gorm:"size:255"`
+	Payload       []byte
+	CreatedAt     time.Time
+	Attempts      int
+	NextAttemptAt time.Time
+	DeliveredAt   *time.Time `gorm:"index"`
+	LastError     string
+}
+
+func (Event) TableName() string {
+	return "outbox_events"
+}
+
+// NewEvent returns Event with payload marshalled to JSON.
+func NewEvent(aggregateType string, aggregateKey string, eventType string, payload any) (Event, error) {
+	bytes, err := json.Marshal(payload)
+	if err != nil {
+		return Event{}, fmt.Errorf("outbox: fail to marshal payload of %s - %w", eventType, err)
+	}
+	return Event{
+		AggregateType: aggregateType,
+		AggregateKey:  aggregateKey,
+		Type:          eventType,
+		Payload:       bytes,
+	}, nil
+}
+
+type Outbox struct {
+	transactionManager data.TransactionManager
+}
+
+func NewOutbox(transactionManager data.TransactionManager) *Outbox {
+	return &Outbox{transactionManager: transactionManager}
+}
+
+// Migrate creates the outbox table.
+func Migrate(db *gorm.DB) error {
+	return db.AutoMigrate(&Event{})
+}
+
+// Append stores event in the transaction of ctx, so it is published only if the transaction commits.
+func (o *Outbox) Append(ctx context.Context, event Event) (Event, error) {
+	db, ok := o.transactionManager.Get(ctx).(*gorm.DB)
+	if !ok {
+		panic("Outbox.Append: fail to get *gorm.DB")
+	}
+	event.ID = 0
+	event.Attempts = 0
+	event.DeliveredAt = nil
+	if event.NextAttemptAt.IsZero() {
+		event.NextAttemptAt = db.NowFunc()
+	}
+	if err := db.Create(&event).Error; err != nil {
+		return event, err
+	}
+	return event, nil
+}
@@ -0,0 +1,87 @@
+package outbox_test
+
+import (
+	"context"
+	"errors"
+	"github.com/reuben-baek/go-learning/e-domain/data"
+	"github.com/reuben-baek/go-learning/e-domain/data/outbox"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"path/filepath"
+	"testing"
+)
+
+type Employee struct {
+	ID   uint
+	Name string
+}
+
+type EmployeeCreated struct {
+	ID   uint   `json:"id"`
+	Name string `json:"name"`
+}
+
+func getGormDB(t *testing.T) *gorm.DB {
+	db, err := gorm.Open(sqlite.Open(filepath.Join(t.TempDir(), "outbox.db")), &gorm.Config{})
+	if err != nil {
+		panic("failed to connect database")
+	}
+	db.AutoMigrate(&Employee{})
+	outbox.Migrate(db)
+	return db
+}
+
+func createEmployee(ctx context.Context, employeeRepository data.Repository[Employee, uint], events *outbox.Outbox, name string) (Employee, error) {
+	created, err := employeeRepository.Create(ctx, Employee{Name: name})
+	if err != nil {
+		return created, err
+	}
+	event, err := outbox.NewEvent("employee", "employee-1", "EmployeeCreated", EmployeeCreated{ID: created.ID, Name: created.Name})
+	if err != nil {
+		return created, err
+	}
+	_, err = events.Append(ctx, event)
+	return created, err
+}
+
+func TestOutbox(t *testing.T) {
+	db := getGormDB(t)
+	transactionManager := data.NewGormTransactionManager(db)
+	employeeRepository := data.NewGormRepository[Employee, uint](transactionManager)
+	events := outbox.NewOutbox(transactionManager)
+	publisher := outbox.NewInMemoryPublisher()
+	relay := outbox.NewRelay(db, publisher)
+
+	ctx := context.Background()
+	t.Run("commit", func(t *testing.T) {
+		err := transactionManager.Do(ctx, func(ctx context.Context) error {
+			_, err := createEmployee(ctx, employeeRepository, events, "reuben.b")
+			return err
+		})
+		assert.Nil(t, err)
+
+		delivered, err := relay.RelayOnce(ctx)
+		assert.Nil(t, err)
+		assert.Equal(t, 1, delivered)
+		published := publisher.Events()
+		assert.Equal(t, 1, len(published))
+		assert.Equal(t, "EmployeeCreated", published[0].Type)
+		assert.JSONEq(t, `{"id": 1, "name": "reuben.b"}`, string(published[0].Payload))
+
+		delivered, err = relay.RelayOnce(ctx)
+		assert.Nil(t, err)
+		assert.Equal(t, 0, delivered)
+	})
+	t.Run("rollback", func(t *testing.T) {
+		err := transactionManager.Do(ctx, func(ctx context.Context) error {
+			createEmployee(ctx, employeeRepository, events, "reuben.b")
+			return errors.New("fail to create employee")
+		})
+		assert.NotNil(t, err)
+
+		delivered, err := relay.RelayOnce(ctx)
+		assert.Nil(t, err)
+		assert.Equal(t, 0, delivered)
+	})
+}
@@ -0,0 +1,99 @@
+package outbox
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+)
+
+type Publisher interface {
+	Publish(ctx context.Context, event Event) error
+}
+
+type PublisherFunc func(ctx context.Context, event Event) error
+
+func (f PublisherFunc) Publish(ctx context.Context, event Event) error {
+	return f(ctx, event)
+}
+
+// InMemoryPublisher keeps published events in memory.
+type InMemoryPublisher struct {
+	m      sync.Mutex
+	events []Event
+}
+
+func NewInMemoryPublisher() *InMemoryPublisher {
+	return &InMemoryPublisher{}
+}
+
+func (p *InMemoryPublisher) Publish(ctx context.Context, event Event) error {
+	p.m.Lock()
+	defer p.m.Unlock()
+	p.events = append(p.events, event)
+	return nil
+}
+
+func (p *InMemoryPublisher) Events() []Event {
+	p.m.Lock()
+	defer p.m.Unlock()
+	return append([]Event{}, p.events...)
+}
+
+// WebhookPublisher posts an event as JSON to url. A response other than 2xx is a failure.
+type WebhookPublisher struct {
+	url    string
+	client *http.Client
+}
+
+func NewWebhookPublisher(url string, client *http.Client) *WebhookPublisher {
+	if client == nil {
+		client = &http.Client{Timeout: 10 * time.Second}
+	}
+	return &WebhookPublisher{url: url, client: client}
+}
+
+type webhookMessage struct {
+	ID            uint            `json:"id"`
+	AggregateType string          `json:"aggregate_type"`
+	AggregateKey  string          `json:"aggregate_key"`
+	Type          string          `json:"type"`
+	Payload       json.RawMessage `json:"payload"`
+	CreatedAt     time.Time       `json:"created_at"`
+}
+
+func (p *WebhookPublisher) Publish(ctx context.Context, event Event) error {
+	payload := json.RawMessage(event.Payload)
+	if len(payload) == 0 {
+		payload = json.RawMessage("null")
+	}
+	body, err := json.Marshal(webhookMessage{
+		ID:            event.ID,
+		AggregateType: event.AggregateType,
+		AggregateKey:  event.AggregateKey,
+		Type:          event.Type,
+		Payload:       payload,
+		CreatedAt:     event.CreatedAt,
+	})
+	if err != nil {
+		return err
+	}
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, p.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	request.Header.Set("Content-Type", "application/json")
+	request.Header.Set("Idempotency-Key", fmt.Sprintf("%d", event.ID))
+	response, err := p.client.Do(request)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+	if response.StatusCode < 200 || response.StatusCode >= 300 {
+		return fmt.Errorf("webhook %s responded %s", p.url, response.Status)
+	}
+	return nil
+}
@@ -0,0 +1,40 @@
+package outbox_test
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/reuben-baek/go-learning/e-domain/data/outbox"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestWebhookPublisher(t *testing.T) {
+	var received map[string]any
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/fail" {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		json.NewDecoder(r.Body).Decode(&received)
+		assert.Equal(t, "7", r.Header.Get("Idempotency-Key"))
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer server.Close()
+
+	event, _ := outbox.NewEvent("employee", "employee-1", "EmployeeCreated", EmployeeCreated{ID: 1, Name: "reuben.b"})
+	event.ID = 7
+
+	t.Run("success", func(t *testing.T) {
+		err := outbox.NewWebhookPublisher(server.URL+"/events", nil).Publish(context.Background(), event)
+		assert.Nil(t, err)
+		assert.Equal(t, "EmployeeCreated", received["type"])
+		assert.Equal(t, "employee-1", received["aggregate_key"])
+		assert.Equal(t, map[string]any{"id": float64(1), "name": "reuben.b"}, received["payload"])
+	})
+	t.Run("fail", func(t *testing.T) {
+		err := outbox.NewWebhookPublisher(server.URL+"/fail", nil).Publish(context.Background(), event)
+		assert.NotNil(t, err)
+	})
+}
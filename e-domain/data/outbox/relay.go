@@ -0,0 +1,135 @@
+package outbox
+
+import (
+	"context"
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+	"time"
+)
+
+// Relay polls undelivered events from the outbox table and delivers them to Publisher.
+// A failed event is retried with exponential backoff, and later events of its AggregateKey wait for it.
+// Only one Relay should run on an outbox table.
+type Relay struct {
+	db           *gorm.DB
+	publisher    Publisher
+	batchSize    int
+	pollInterval time.Duration
+	minBackoff   time.Duration
+	maxBackoff   time.Duration
+}
+
+type RelayOption func(r *Relay)
+
+func WithBatchSize(batchSize int) RelayOption {
+	return func(r *Relay) {
+		r.batchSize = batchSize
+	}
+}
+
+func WithPollInterval(pollInterval time.Duration) RelayOption {
+	return func(r *Relay) {
+		r.pollInterval = pollInterval
+	}
+}
+
+// WithBackoff sets the delay before the first retry, which doubles on each failure up to max.
+func WithBackoff(min time.Duration, max time.Duration) RelayOption {
+	return func(r *Relay) {
+		r.minBackoff = min
+		r.maxBackoff = max
+	}
+}
+
+func NewRelay(db *gorm.DB, publisher Publisher, options ...RelayOption) *Relay {
+	r := &Relay{
+		db:           db,
+		publisher:    publisher,
+		batchSize:    100,
+		pollInterval: time.Second,
+		minBackoff:   time.Second,
+		maxBackoff:   5 * time.Minute,
+	}
+	for _, option := range options {
+		option(r)
+	}
+	return r
+}
+
+// Run relays events every poll interval until ctx is done.
+func (r *Relay) Run(ctx context.Context) error {
+	ticker := time.NewTicker(r.pollInterval)
+	defer ticker.Stop()
+	for {
+		if _, err := r.RelayOnce(ctx); err != nil {
+			logrus.Errorf("Relay.Run: %v", err)
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// RelayOnce delivers a batch of due events and returns the number of delivered events.
+// Events waiting for backoff, and later events of their AggregateKey, are filtered out by the query before the batch
+// is limited, so an aggregate failing for long does not fill batches and hold back events of others.
+func (r *Relay) RelayOnce(ctx context.Context) (int, error) {
+	db := r.db.WithContext(ctx)
+	now := db.NowFunc()
+	var events []Event
+	blocking := db.Table(Event{}.TableName()+" AS blocking").Select("1").
+		Where("blocking.aggregate_key = outbox_events.aggregate_key AND blocking.aggregate_key <> ''").
+		Where("blocking.delivered_at IS NULL AND blocking.next_attempt_at > ? AND blocking.id < outbox_events.id", now)
+	if err := db.Where("delivered_at IS NULL AND next_attempt_at <= ?", now).
+		Where("NOT EXISTS (?)", blocking).
+		Order("id").Limit(r.batchSize).Find(&events).Error; err != nil {
+		return 0, err
+	}
+
+	delivered := 0
+	blocked := make(map[string]bool)
+	for _, event := range events {
+		// later events of an aggregate failed in this batch wait for it
+		if event.AggregateKey != "" && blocked[event.AggregateKey] {
+			continue
+		}
+
+		if err := r.publisher.Publish(ctx, event); err != nil {
+			blocked[event.AggregateKey] = true
+			attempts := event.Attempts + 1
+			nextAttemptAt := now.Add(r.backoff(attempts))
+			logrus.Warnf("Relay.RelayOnce: fail to publish event[%d] %s, attempts[%d], next attempt at %v - %v", event.ID, event.Type, attempts, nextAttemptAt, err)
+			if err := db.Model(&event).Updates(map[string]any{
+				"attempts":        attempts,
+				"next_attempt_at": nextAttemptAt,
+				"last_error":      err.Error(),
+			}).Error; err != nil {
+				return delivered, err
+			}
+			continue
+		}
+
+		if err := db.Model(&event).Updates(map[string]any{
+			"attempts":     event.Attempts + 1,
+			"delivered_at": now,
+			"last_error":   "",
+		}).Error; err != nil {
+			return delivered, err
+		}
+		delivered++
+	}
+	return delivered, nil
+}
+
+func (r *Relay) backoff(attempts int) time.Duration {
+	backoff := r.minBackoff
+	for i := 1; i < attempts && backoff < r.maxBackoff; i++ {
+		backoff *= 2
+	}
+	if backoff > r.maxBackoff {
+		backoff = r.maxBackoff
+	}
+	return backoff
+}
@@ -0,0 +1,128 @@
+package outbox_test
+
+import (
+	"context"
+	"errors"
+	"github.com/reuben-baek/go-learning/e-domain/data"
+	"github.com/reuben-baek/go-learning/e-domain/data/outbox"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestRelay(t *testing.T) {
+	db := getGormDB(t)
+	transactionManager := data.NewGormTransactionManager(db)
+	events := outbox.NewOutbox(transactionManager)
+
+	ctx := context.Background()
+	appendEvent := func(key string, eventType string) {
+		transactionManager.Do(ctx, func(ctx context.Context) error {
+			event, _ := outbox.NewEvent("employee", key, eventType, nil)
+			_, err := events.Append(ctx, event)
+			return err
+		})
+	}
+	appendEvent("employee-1", "EmployeeCreated")
+	appendEvent("employee-2", "EmployeeCreated")
+	appendEvent("employee-1", "EmployeeUpdated")
+
+	failing := true
+	var published []string
+	publisher := outbox.PublisherFunc(func(ctx context.Context, event outbox.Event) error {
+		if failing && event.AggregateKey == "employee-1" {
+			return errors.New("broker is unavailable")
+		}
+		published = append(published, event.AggregateKey+":"+event.Type)
+		return nil
+	})
+	relay := outbox.NewRelay(db, publisher, outbox.WithBackoff(30*time.Millisecond, time.Second))
+
+	t.Run("failed event blocks later events of its aggregate", func(t *testing.T) {
+		delivered, err := relay.RelayOnce(ctx)
+		assert.Nil(t, err)
+		assert.Equal(t, 1, delivered)
+		assert.Equal(t, []string{"employee-2:EmployeeCreated"}, published)
+
+		var failed outbox.Event
+		db.First(&failed, 1)
+		assert.Equal(t, 1, failed.Attempts)
+		assert.Equal(t, "broker is unavailable", failed.LastError)
+		assert.Nil(t, failed.DeliveredAt)
+	})
+	t.Run("retry after backoff", func(t *testing.T) {
+		failing = false
+		delivered, err := relay.RelayOnce(ctx)
+		assert.Nil(t, err)
+		assert.Equal(t, 0, delivered)
+
+		time.Sleep(40 * time.Millisecond)
+		delivered, err = relay.RelayOnce(ctx)
+		assert.Nil(t, err)
+		assert.Equal(t, 2, delivered)
+		assert.Equal(t, []string{
+			"employee-2:EmployeeCreated",
+			"employee-1:EmployeeCreated",
+			"employee-1:EmployeeUpdated",
+		}, published)
+	})
+	t.Run("run", func(t *testing.T) {
+		appendEvent("employee-3", "EmployeeCreated")
+		ctx, cancel := context.WithTimeout(ctx, 50*time.Millisecond)
+		defer cancel()
+		err := outbox.NewRelay(db, publisher, outbox.WithPollInterval(10*time.Millisecond)).Run(ctx)
+		assert.ErrorIs(t, err, context.DeadlineExceeded)
+		assert.Equal(t, "employee-3:EmployeeCreated", published[len(published)-1])
+	})
+}
+
+func TestRelay_FailingAggregate(t *testing.T) {
+	db := getGormDB(t)
+	transactionManager := data.NewGormTransactionManager(db)
+	events := outbox.NewOutbox(transactionManager)
+
+	ctx := context.Background()
+	appendEvent := func(key string, eventType string) {
+		transactionManager.Do(ctx, func(ctx context.Context) error {
+			event, _ := outbox.NewEvent("employee", key, eventType, nil)
+			_, err := events.Append(ctx, event)
+			return err
+		})
+	}
+	// more events of the failing aggregate than a batch, before events of others
+	for i := 0; i < 3; i++ {
+		appendEvent("employee-1", "EmployeeUpdated")
+	}
+	appendEvent("employee-2", "EmployeeCreated")
+	appendEvent("employee-3", "EmployeeCreated")
+
+	var published []string
+	publisher := outbox.PublisherFunc(func(ctx context.Context, event outbox.Event) error {
+		if event.AggregateKey == "employee-1" {
+			return errors.New("broker rejects employee-1")
+		}
+		published = append(published, event.AggregateKey+":"+event.Type)
+		return nil
+	})
+	relay := outbox.NewRelay(db, publisher, outbox.WithBatchSize(2), outbox.WithBackoff(time.Minute, time.Hour))
+
+	delivered, err := relay.RelayOnce(ctx)
+	assert.Nil(t, err)
+	assert.Equal(t, 0, delivered)
+
+	delivered, err = relay.RelayOnce(ctx)
+	assert.Nil(t, err)
+	assert.Equal(t, 2, delivered)
+	assert.Equal(t, []string{"employee-2:EmployeeCreated", "employee-3:EmployeeCreated"}, published)
+
+	appendEvent("employee-2", "EmployeeUpdated")
+	delivered, err = relay.RelayOnce(ctx)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, delivered)
+	assert.Equal(t, "employee-2:EmployeeUpdated", published[len(published)-1])
+
+	var failed outbox.Event
+	db.First(&failed, 1)
+	assert.Equal(t, 1, failed.Attempts)
+	assert.Nil(t, failed.DeliveredAt)
+}
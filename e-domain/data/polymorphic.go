@@ -0,0 +1,54 @@
+package data
+
+import (
+	"fmt"
+	"reflect"
+	"sync"
+)
+
+// polymorphicTypes maps values of polymorphic type columns, such as owner_type, to entity types and back.
+var polymorphicTypes = struct {
+	m      sync.RWMutex
+	types  map[string]reflect.Type
+	values map[reflect.Type]string
+}{
+	types:  make(map[string]reflect.Type),
+	values: make(map[reflect.Type]string),
+}
+
+// RegisterPolymorphicType registers entity as an owner of polymorphic belong-to associations, stored as value
+// in their type column. value should be the polymorphic value of GORM, which is the table name of entity by default.
+func RegisterPolymorphicType(value string, entity any) {
+	entityType := reflect.TypeOf(entity)
+	if entityType.Kind() == reflect.Pointer {
+		entityType = entityType.Elem()
+	}
+	if entityType.Kind() != reflect.Struct {
+		panic(fmt.Sprintf("RegisterPolymorphicType: entity[%s] is not struct type", entityType.String()))
+	}
+	polymorphicTypes.m.Lock()
+	defer polymorphicTypes.m.Unlock()
+	polymorphicTypes.types[value] = entityType
+	polymorphicTypes.values[entityType] = value
+}
+
+func polymorphicTypeOf(value any) (reflect.Type, bool) {
+	if value == nil {
+		return nil, false
+	}
+	polymorphicTypes.m.RLock()
+	defer polymorphicTypes.m.RUnlock()
+	entityType, ok := polymorphicTypes.types[batchKey(value)]
+	return entityType, ok
+}
+
+func polymorphicValueOf(entityType reflect.Type) (string, bool) {
+	polymorphicTypes.m.RLock()
+	defer polymorphicTypes.m.RUnlock()
+	value, ok := polymorphicTypes.values[entityType]
+	return value, ok
+}
+
+func isPolymorphicBelongTo(association Association) bool {
+	return association.Type == BelongTo && association.Polymorphic != ""
+}
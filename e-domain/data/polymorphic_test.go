@@ -0,0 +1,136 @@
+package data_test
+
+import (
+	"context"
+	"github.com/reuben-baek/go-learning/e-domain/data"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+type Commentable interface {
+	OwnerName() string
+}
+
+// polymorphic entities are declared at package level to implement Commentable.
+type PolyProduct struct {
+	data.LazyLoader `gorm:"-"`
+	ID              uint
+	Name            string
+	Comments        []PolyComment `gorm:"polymorphic:Owner"`
+}
+
+func (p PolyProduct) OwnerName() string {
+	return "product " + p.Name
+}
+
+type PolyEmployee struct {
+	data.LazyLoader `gorm:"-"`
+	ID              uint
+	Name            string
+	Comments        []PolyComment `gorm:"polymorphic:Owner"`
+}
+
+func (e *PolyEmployee) OwnerName() string {
+	return "employee " + e.Name
+}
+
+type PolyComment struct {
+	data.LazyLoader `gorm:"-"`
+	ID              uint
+	Body            string
+	OwnerID         uint
+	OwnerType       string
+	Owner           Commentable `gorm:"-"`
+}
+
+func init() {
+	data.RegisterPolymorphicType("poly_products", PolyProduct{})
+	data.RegisterPolymorphicType("poly_employees", PolyEmployee{})
+}
+
+func TestGormRepository_Polymorphic(t *testing.T) {
+	db := getGormDB()
+	db.AutoMigrate(&PolyProduct{}, &PolyEmployee{}, &PolyComment{})
+
+	transactionManager := data.NewGormTransactionManager(db)
+	productRepository := data.NewGormRepository[PolyProduct, uint](transactionManager)
+	commentRepository := data.NewGormRepository[PolyComment, uint](transactionManager)
+
+	// product and employee of the same ID
+	storage := PolyProduct{ID: 1, Name: "storage", Comments: []PolyComment{{Body: "fast"}, {Body: "cheap"}}}
+	reuben := PolyEmployee{ID: 1, Name: "reuben", Comments: []PolyComment{{Body: "kind"}}}
+	db.Create(&storage)
+	db.Create(&reuben)
+	unknown := PolyComment{Body: "lost", OwnerID: 1, OwnerType: "poly_unknowns"}
+	db.Create(&unknown)
+
+	bodies := func(comments []PolyComment) []string {
+		var bodies []string
+		for _, comment := range comments {
+			bodies = append(bodies, comment.Body)
+		}
+		return bodies
+	}
+
+	ctx := context.Background()
+	t.Run("belongs-to polymorphic owner", func(t *testing.T) {
+		err := transactionManager.Do(ctx, func(ctx context.Context) error {
+			comments, err := commentRepository.FindBy(ctx, "Owner", storage)
+			assert.Nil(t, err)
+			assert.Equal(t, []string{"fast", "cheap"}, bodies(comments))
+
+			owner, err := data.LazyLoadNow[Commentable]("Owner", &comments[0])
+			assert.Nil(t, err)
+			assert.Equal(t, "product storage", owner.OwnerName())
+			assert.IsType(t, PolyProduct{}, owner)
+
+			comments, err = commentRepository.FindBy(ctx, "Owner", reuben)
+			assert.Nil(t, err)
+			assert.Equal(t, []string{"kind"}, bodies(comments))
+
+			owner, err = data.LazyLoadNow[Commentable]("Owner", &comments[0])
+			assert.Nil(t, err)
+			assert.Equal(t, "employee reuben", owner.OwnerName())
+			assert.IsType(t, &PolyEmployee{}, owner)
+
+			lost, err := commentRepository.FindOne(ctx, unknown.ID)
+			assert.Nil(t, err)
+			_, err = data.LazyLoadNow[Commentable]("Owner", &lost)
+			assert.ErrorContains(t, err, "has not registered polymorphic type poly_unknowns")
+			return nil
+		})
+		assert.Nil(t, err)
+	})
+	t.Run("has-many polymorphic comments", func(t *testing.T) {
+		err := transactionManager.Do(ctx, func(ctx context.Context) error {
+			product, err := productRepository.FindOne(ctx, storage.ID)
+			assert.Nil(t, err)
+			comments, err := data.LazyLoadNow[[]PolyComment]("Comments", &product)
+			assert.Nil(t, err)
+			assert.Equal(t, []string{"fast", "cheap"}, bodies(comments))
+
+			products, err := productRepository.FindBy(ctx, "Comment", comments[1])
+			assert.Nil(t, err)
+			assert.Equal(t, 1, len(products))
+			assert.Equal(t, "storage", products[0].Name)
+
+			products, err = productRepository.FindBy(ctx, "Comment", reuben.Comments[0])
+			assert.Nil(t, err)
+			assert.Equal(t, 0, len(products))
+			return nil
+		})
+		assert.Nil(t, err)
+	})
+	t.Run("in-memory", func(t *testing.T) {
+		dummyTransactionManager := data.NewDummyTransactionManager()
+		repository := data.NewInMemoryRepository[PolyComment, uint](dummyTransactionManager)
+		dummyTransactionManager.Do(ctx, func(ctx context.Context) error {
+			repository.Create(ctx, PolyComment{ID: 1, Body: "fast", OwnerID: 1, OwnerType: "poly_products"})
+			repository.Create(ctx, PolyComment{ID: 2, Body: "kind", OwnerID: 1, OwnerType: "poly_employees"})
+			comments, err := repository.FindBy(ctx, "Owner", reuben)
+			assert.Nil(t, err)
+			assert.Equal(t, []string{"kind"}, bodies(comments))
+			return nil
+		})
+	})
+}
@@ -0,0 +1,125 @@
+package data
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/sirupsen/logrus"
+	"reflect"
+	"sync"
+)
+
+const DefaultPrefetchConcurrency = 4
+
+type prefetchConcurrencyKey struct{}
+
+// WithPrefetchConcurrency returns a context in which Prefetch runs at most n loads at once.
+func WithPrefetchConcurrency(ctx context.Context, n int) context.Context {
+	if n <= 0 {
+		panic(fmt.Sprintf("WithPrefetchConcurrency: wrong concurrency - %d", n))
+	}
+	return context.WithValue(ctx, prefetchConcurrencyKey{}, n)
+}
+
+type prefetchable interface {
+	prefetch() error
+}
+
+func (l *LazyLoad[T]) prefetch() error {
+	_, err := l.Load()
+	return err
+}
+
+// Prefetch loads the named lazy associations of ptrToEntity concurrently, and waits for them.
+// ptrToEntity is either LazyLoadable, whose load functions are called and loaded values are set to the fields,
+// or a struct with Lazy fields, which are loaded. Without names, all lazy associations are loaded.
+// The load functions run in the transaction session of the context they were found with,
+// and an error of the loads is returned after all loads are done.
+func Prefetch(ctx context.Context, ptrToEntity any, names ...string) error {
+	loads := prefetchLoads(ptrToEntity, names)
+	concurrency, ok := ctx.Value(prefetchConcurrencyKey{}).(int)
+	if !ok {
+		concurrency = DefaultPrefetchConcurrency
+	}
+	logrus.Debugf("Prefetch: %d loads of %T, concurrency %d", len(loads), ptrToEntity, concurrency)
+
+	semaphore := make(chan struct{}, concurrency)
+	errs := make([]error, len(loads))
+	var wg sync.WaitGroup
+	for i, load := range loads {
+		select {
+		case semaphore <- struct{}{}:
+		case <-ctx.Done():
+			errs[i] = ctx.Err()
+			continue
+		}
+		wg.Add(1)
+		go func(i int, load func() error) {
+			defer func() {
+				<-semaphore
+				wg.Done()
+			}()
+			errs[i] = load()
+		}(i, load)
+	}
+	wg.Wait()
+	return errors.Join(errs...)
+}
+
+func prefetchLoads(ptrToEntity any, names []string) []func() error {
+	var loads []func() error
+	if lazyLoader, ok := ptrToEntity.(LazyLoadable); ok {
+		if len(names) == 0 {
+			names = lazyLoader.Entities()
+		}
+		for _, name := range names {
+			name := name
+			if !lazyLoader.HasLoadFunc(name) {
+				continue // loaded or eager
+			}
+			field := reflect.Indirect(reflect.ValueOf(lazyLoader)).FieldByName(name)
+			emptyEntity := reflect.Zero(field.Type()).Interface()
+			loads = append(loads, func() error {
+				if _, _, err := loadField(name, lazyLoader, emptyEntity); err != nil {
+					return fmt.Errorf("prefetch %s: %w", name, err)
+				}
+				return nil
+			})
+		}
+		return loads
+	}
+
+	value := reflect.ValueOf(ptrToEntity)
+	if value.Kind() != reflect.Pointer || value.Elem().Kind() != reflect.Struct {
+		panic(fmt.Sprintf("Prefetch: %T is not pointer to struct", ptrToEntity))
+	}
+	value = value.Elem()
+	if len(names) == 0 {
+		for i := 0; i < value.NumField(); i++ {
+			if value.Type().Field(i).IsExported() {
+				names = append(names, value.Type().Field(i).Name)
+			}
+		}
+	}
+	for _, name := range names {
+		name := name
+		field := value.FieldByName(name)
+		if !field.IsValid() {
+			panic(fmt.Sprintf("Prefetch: %s has no field %s", value.Type(), name))
+		}
+		if (field.Kind() != reflect.Pointer && field.Kind() != reflect.Interface) || field.IsNil() {
+			continue
+		}
+		lazy, ok := field.Interface().(prefetchable)
+		if !ok {
+			continue
+		}
+		loads = append(loads, func() error {
+			if err := lazy.prefetch(); err != nil {
+				return fmt.Errorf("prefetch %s: %w", name, err)
+			}
+			return nil
+		})
+	}
+	return loads
+}
@@ -0,0 +1,122 @@
+package data_test
+
+import (
+	"context"
+	"github.com/reuben-baek/go-learning/e-domain/data"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestPrefetch(t *testing.T) {
+	type Company struct {
+		ID   uint
+		Name string
+	}
+	type Category struct {
+		ID   uint
+		Name string
+	}
+	type Tag struct {
+		ID   uint
+		Name string
+	}
+	type Product struct {
+		data.LazyLoader `gorm:"-"`
+		ID              uint
+		Name            string
+		CompanyID       uint
+		Company         Company
+		CategoryID      uint
+		Category        Category
+		Tags            []Tag `gorm:"many2many:product_tags;"`
+	}
+
+	db := getGormFileDB(t)
+	db.AutoMigrate(&Company{}, &Category{}, &Tag{}, &Product{})
+
+	var inFlight, maxInFlight atomic.Int32
+	db.Callback().Query().Before("gorm:query").Register("test:in_flight", func(db *gorm.DB) {
+		n := inFlight.Add(1)
+		for {
+			if max := maxInFlight.Load(); n <= max || maxInFlight.CompareAndSwap(max, n) {
+				break
+			}
+		}
+		time.Sleep(20 * time.Millisecond)
+	})
+	db.Callback().Query().After("gorm:query").Register("test:in_flight_done", func(db *gorm.DB) {
+		inFlight.Add(-1)
+	})
+
+	transactionManager := data.NewGormTransactionManager(db)
+	productRepository := data.NewGormRepository[Product, uint](transactionManager)
+
+	kakao := Company{Name: "kakao"}
+	db.Create(&kakao)
+	cloud := Category{Name: "cloud"}
+	db.Create(&cloud)
+	ssd := Tag{Name: "ssd"}
+	db.Create(&ssd)
+	macbook := Product{Name: "macbook", CompanyID: kakao.ID, CategoryID: cloud.ID, Tags: []Tag{ssd}}
+	db.Create(&macbook)
+
+	ctx := context.Background()
+	t.Run("concurrently in transaction", func(t *testing.T) {
+		maxInFlight.Store(0)
+		err := transactionManager.Do(ctx, func(ctx context.Context) error {
+			found, err := productRepository.FindOne(ctx, macbook.ID)
+			if err != nil {
+				return err
+			}
+			if err := data.Prefetch(ctx, &found); err != nil {
+				return err
+			}
+			assert.Equal(t, kakao, found.Company)
+			assert.Equal(t, cloud, found.Category)
+			assert.Equal(t, []Tag{ssd}, found.Tags)
+			assert.Empty(t, found.Entities())
+			return nil
+		})
+		assert.Nil(t, err)
+		assert.Greater(t, maxInFlight.Load(), int32(1))
+	})
+	t.Run("concurrency limit", func(t *testing.T) {
+		maxInFlight.Store(0)
+		found, _ := productRepository.FindOne(ctx, macbook.ID)
+		err := data.Prefetch(data.WithPrefetchConcurrency(ctx, 1), &found, "Company", "Category")
+		assert.Nil(t, err)
+		assert.Equal(t, int32(1), maxInFlight.Load())
+		assert.Equal(t, kakao, found.Company)
+		assert.Equal(t, []string{"Tags"}, found.Entities())
+	})
+	t.Run("lazy fields", func(t *testing.T) {
+		type Model struct {
+			Company  data.Lazy[Company]
+			Category *data.LazyLoad[Category]
+			Name     string
+		}
+		found, _ := productRepository.FindOne(ctx, macbook.ID)
+		model := Model{
+			Company:  data.LazyLoadTo("Company", &found, func(c Company) Company { return c }),
+			Category: data.LazyLoadTo("Category", &found, func(c Category) Category { return c }),
+		}
+		err := data.Prefetch(ctx, &model)
+		assert.Nil(t, err)
+		assert.Equal(t, kakao, data.MustGetLazy(model.Company))
+		assert.Equal(t, cloud, model.Category.MustGet())
+	})
+	t.Run("errors after the transaction", func(t *testing.T) {
+		var found Product
+		transactionManager.Do(ctx, func(ctx context.Context) error {
+			found, _ = productRepository.FindOne(ctx, macbook.ID)
+			return nil
+		})
+		err := data.Prefetch(ctx, &found, "Company", "Tags")
+		assert.ErrorIs(t, err, data.TransactionClosedError)
+		assert.ErrorContains(t, err, "prefetch Company")
+		assert.ErrorContains(t, err, "prefetch Tags")
+	})
+}
@@ -12,3 +12,8 @@ type Repository[T any, ID comparable] interface {
 type FindByRepository[T any, S any] interface {
 	FindBy(ctx context.Context, name string, byEntity S) ([]T, error)
 }
+
+// FindByValueRepository finds entities by a value object, such as orders shipped to an address.
+type FindByValueRepository[T any] interface {
+	FindByValue(ctx context.Context, name string, value any) ([]T, error)
+}
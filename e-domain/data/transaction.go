@@ -1,8 +1,99 @@
 package data
 
-import "context"
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
 
 type TransactionManager interface {
 	Do(ctx context.Context, f func(ctx context.Context) error) error
 	Get(ctx context.Context) any
 }
+
+// DataSourceTransactionManager is a TransactionManager of several named datasources.
+type DataSourceTransactionManager interface {
+	TransactionManager
+	DoOn(ctx context.Context, dataSources []string, f func(ctx context.Context) error) error
+	GetDataSource(ctx context.Context, dataSource string) any
+}
+
+// ReadTransactionManager is a TransactionManager which routes read-only queries, e.g. to read replicas.
+// Get is used for writes and GetForRead for reads.
+type ReadTransactionManager interface {
+	TransactionManager
+	GetForRead(ctx context.Context) any
+}
+
+// ReadDataSourceTransactionManager is a ReadTransactionManager of several named datasources.
+// GetDataSourceForRead is used for reads of the named datasource.
+type ReadDataSourceTransactionManager interface {
+	ReadTransactionManager
+	DataSourceTransactionManager
+	GetDataSourceForRead(ctx context.Context, dataSource string) any
+}
+
+type transactionTimeoutKey struct{}
+
+// WithTransactionTimeout returns a context whose transaction started by TransactionManager.Do times out after timeout.
+// When it times out, the transaction is rolled back and Do returns context.DeadlineExceeded.
+func WithTransactionTimeout(ctx context.Context, timeout time.Duration) context.Context {
+	return context.WithValue(ctx, transactionTimeoutKey{}, timeout)
+}
+
+func transactionTimeout(ctx context.Context, defaultTimeout time.Duration) time.Duration {
+	if timeout, ok := ctx.Value(transactionTimeoutKey{}).(time.Duration); ok {
+		return timeout
+	}
+	return defaultTimeout
+}
+
+var TransactionClosedError = errors.New("transaction context is used after the transaction completed")
+
+type TransactionOp string
+
+const (
+	TransactionBegin    TransactionOp = "begin"
+	TransactionCommit   TransactionOp = "commit"
+	TransactionRollback TransactionOp = "rollback"
+)
+
+// TransactionError is returned by TransactionManager.Do when begin, commit or rollback fails.
+// Cause is the error returned by the callback, if any.
+type TransactionError struct {
+	Op    TransactionOp
+	Err   error
+	Cause error
+}
+
+func (e *TransactionError) Error() string {
+	if e.Cause != nil {
+		return fmt.Sprintf("fail to %s transaction: %v (cause: %v)", e.Op, e.Err, e.Cause)
+	}
+	return fmt.Sprintf("fail to %s transaction: %v", e.Op, e.Err)
+}
+
+func (e *TransactionError) Unwrap() []error {
+	if e.Cause != nil {
+		return []error{e.Err, e.Cause}
+	}
+	return []error{e.Err}
+}
+
+// PanicError is a panic recovered by TransactionManager.Do.
+type PanicError struct {
+	Value any
+	Stack []byte
+}
+
+func (e *PanicError) Error() string {
+	return fmt.Sprintf("panic in transaction: %v", e.Value)
+}
+
+func (e *PanicError) Unwrap() error {
+	if err, ok := e.Value.(error); ok {
+		return err
+	}
+	return nil
+}
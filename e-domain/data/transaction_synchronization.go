@@ -0,0 +1,176 @@
+package data
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+)
+
+var NoTransactionError = errors.New("no transaction")
+
+type TransactionStatus int
+
+const (
+	StatusCommitted TransactionStatus = iota + 1
+	StatusRolledBack
+)
+
+func (s TransactionStatus) String() string {
+	switch s {
+	case StatusCommitted:
+		return "committed"
+	case StatusRolledBack:
+		return "rolled-back"
+	default:
+		return "unknown"
+	}
+}
+
+// transactionSynchronization holds callbacks registered on the current transaction.
+// Callbacks are executed in registration order by a TransactionManager.
+type transactionSynchronization struct {
+	m               sync.Mutex
+	beforeCommit    []func(ctx context.Context) error
+	afterCommit     []func(ctx context.Context)
+	afterRollback   []func(ctx context.Context)
+	afterCompletion []func(ctx context.Context, status TransactionStatus)
+	completed       atomic.Bool
+	resources       map[any]any
+}
+
+type transactionSynchronizationKey struct{}
+
+func withTransactionSynchronization(ctx context.Context) (context.Context, *transactionSynchronization) {
+	s := &transactionSynchronization{}
+	return context.WithValue(ctx, transactionSynchronizationKey{}, s), s
+}
+
+func getTransactionSynchronization(ctx context.Context) (*transactionSynchronization, error) {
+	s, ok := ctx.Value(transactionSynchronizationKey{}).(*transactionSynchronization)
+	if !ok {
+		return nil, NoTransactionError
+	}
+	if s.completed.Load() {
+		return nil, TransactionClosedError
+	}
+	return s, nil
+}
+
+// BeforeCommit registers fn to be called before the current transaction commits.
+// An error returned by fn aborts the commit and rolls the transaction back.
+func BeforeCommit(ctx context.Context, fn func(ctx context.Context) error) error {
+	s, err := getTransactionSynchronization(ctx)
+	if err != nil {
+		return err
+	}
+	s.m.Lock()
+	defer s.m.Unlock()
+	s.beforeCommit = append(s.beforeCommit, fn)
+	return nil
+}
+
+// AfterCommit registers fn to be called after the current transaction has committed.
+// fn is called with the context given to TransactionManager.Do, not with the transaction context.
+func AfterCommit(ctx context.Context, fn func(ctx context.Context)) error {
+	s, err := getTransactionSynchronization(ctx)
+	if err != nil {
+		return err
+	}
+	s.m.Lock()
+	defer s.m.Unlock()
+	s.afterCommit = append(s.afterCommit, fn)
+	return nil
+}
+
+// AfterRollback registers fn to be called after the current transaction has rolled back.
+func AfterRollback(ctx context.Context, fn func(ctx context.Context)) error {
+	s, err := getTransactionSynchronization(ctx)
+	if err != nil {
+		return err
+	}
+	s.m.Lock()
+	defer s.m.Unlock()
+	s.afterRollback = append(s.afterRollback, fn)
+	return nil
+}
+
+// AfterCompletion registers fn to be called after the current transaction has committed or rolled back.
+// It is called after AfterCommit or AfterRollback callbacks.
+func AfterCompletion(ctx context.Context, fn func(ctx context.Context, status TransactionStatus)) error {
+	s, err := getTransactionSynchronization(ctx)
+	if err != nil {
+		return err
+	}
+	s.m.Lock()
+	defer s.m.Unlock()
+	s.afterCompletion = append(s.afterCompletion, fn)
+	return nil
+}
+
+// transactionResource returns the resource of key bound to the transaction of ctx.
+// The resource is created by newResource at first.
+func transactionResource(ctx context.Context, key any, newResource func() any) (any, bool) {
+	s, err := getTransactionSynchronization(ctx)
+	if err != nil {
+		return nil, false
+	}
+	s.m.Lock()
+	defer s.m.Unlock()
+	if s.resources == nil {
+		s.resources = make(map[any]any)
+	}
+	resource, ok := s.resources[key]
+	if !ok {
+		resource = newResource()
+		s.resources[key] = resource
+	}
+	return resource, true
+}
+
+// triggerBeforeCommit calls BeforeCommit callbacks until one fails.
+// Callbacks may register further callbacks, which are called in the same pass.
+func (s *transactionSynchronization) triggerBeforeCommit(ctx context.Context) error {
+	for i := 0; ; i++ {
+		s.m.Lock()
+		if i >= len(s.beforeCommit) {
+			s.m.Unlock()
+			return nil
+		}
+		fn := s.beforeCommit[i]
+		s.m.Unlock()
+		if err := fn(ctx); err != nil {
+			return err
+		}
+	}
+}
+
+func (s *transactionSynchronization) triggerAfterCommit(ctx context.Context) {
+	s.m.Lock()
+	afterCommit := append([]func(ctx context.Context){}, s.afterCommit...)
+	s.m.Unlock()
+	for _, fn := range afterCommit {
+		fn(ctx)
+	}
+	s.triggerAfterCompletion(ctx, StatusCommitted)
+}
+
+func (s *transactionSynchronization) triggerAfterRollback(ctx context.Context) {
+	s.m.Lock()
+	afterRollback := append([]func(ctx context.Context){}, s.afterRollback...)
+	s.m.Unlock()
+	for _, fn := range afterRollback {
+		fn(ctx)
+	}
+	s.triggerAfterCompletion(ctx, StatusRolledBack)
+}
+
+func (s *transactionSynchronization) triggerAfterCompletion(ctx context.Context, status TransactionStatus) {
+	s.completed.Store(true)
+	s.m.Lock()
+	afterCompletion := append([]func(ctx context.Context, status TransactionStatus){}, s.afterCompletion...)
+	s.m.Unlock()
+	for _, fn := range afterCompletion {
+		fn(ctx, status)
+	}
+}
@@ -0,0 +1,131 @@
+package data_test
+
+import (
+	"context"
+	"errors"
+	"github.com/reuben-baek/go-learning/e-domain/data"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+	"testing"
+)
+
+func TestTransactionSynchronization(t *testing.T) {
+	type User struct {
+		gorm.Model
+		Name string
+	}
+
+	db := getGormDB()
+	db.AutoMigrate(&User{})
+
+	transactionManagers := map[string]data.TransactionManager{
+		"gorm":  data.NewGormTransactionManager(db),
+		"dummy": data.NewDummyTransactionManager(),
+	}
+
+	for name, transactionManager := range transactionManagers {
+		t.Run(name, func(t *testing.T) {
+			t.Run("commit", func(t *testing.T) {
+				var called []string
+				err := transactionManager.Do(context.Background(), func(ctx context.Context) error {
+					assert.Nil(t, data.BeforeCommit(ctx, func(ctx context.Context) error {
+						called = append(called, "before-commit")
+						return nil
+					}))
+					assert.Nil(t, data.AfterCommit(ctx, func(ctx context.Context) {
+						called = append(called, "after-commit")
+					}))
+					assert.Nil(t, data.AfterRollback(ctx, func(ctx context.Context) {
+						called = append(called, "after-rollback")
+					}))
+					assert.Nil(t, data.AfterCompletion(ctx, func(ctx context.Context, status data.TransactionStatus) {
+						called = append(called, "after-completion:"+status.String())
+					}))
+					assert.Empty(t, called)
+					return nil
+				})
+				assert.Nil(t, err)
+				assert.Equal(t, []string{"before-commit", "after-commit", "after-completion:committed"}, called)
+			})
+			t.Run("rollback", func(t *testing.T) {
+				var called []string
+				err := transactionManager.Do(context.Background(), func(ctx context.Context) error {
+					data.BeforeCommit(ctx, func(ctx context.Context) error {
+						called = append(called, "before-commit")
+						return nil
+					})
+					data.AfterCommit(ctx, func(ctx context.Context) {
+						called = append(called, "after-commit")
+					})
+					data.AfterRollback(ctx, func(ctx context.Context) {
+						called = append(called, "after-rollback")
+					})
+					data.AfterCompletion(ctx, func(ctx context.Context, status data.TransactionStatus) {
+						called = append(called, "after-completion:"+status.String())
+					})
+					return errors.New("fail to save")
+				})
+				assert.NotNil(t, err)
+				assert.Equal(t, []string{"after-rollback", "after-completion:rolled-back"}, called)
+			})
+			t.Run("before-commit error aborts commit", func(t *testing.T) {
+				var called []string
+				beforeCommitError := errors.New("before commit error")
+				err := transactionManager.Do(context.Background(), func(ctx context.Context) error {
+					data.BeforeCommit(ctx, func(ctx context.Context) error {
+						called = append(called, "before-commit-1")
+						return beforeCommitError
+					})
+					data.BeforeCommit(ctx, func(ctx context.Context) error {
+						called = append(called, "before-commit-2")
+						return nil
+					})
+					data.AfterCommit(ctx, func(ctx context.Context) {
+						called = append(called, "after-commit")
+					})
+					data.AfterRollback(ctx, func(ctx context.Context) {
+						called = append(called, "after-rollback")
+					})
+					return nil
+				})
+				assert.Equal(t, beforeCommitError, err)
+				assert.Equal(t, []string{"before-commit-1", "after-rollback"}, called)
+			})
+			t.Run("rollback on panic", func(t *testing.T) {
+				var called []string
+				assert.Panics(t, func() {
+					transactionManager.Do(context.Background(), func(ctx context.Context) error {
+						data.AfterRollback(ctx, func(ctx context.Context) {
+							called = append(called, "after-rollback")
+						})
+						panic("something wrong")
+					})
+				})
+				assert.Equal(t, []string{"after-rollback"}, called)
+			})
+		})
+	}
+
+	t.Run("gorm before-commit error rolls back", func(t *testing.T) {
+		transactionManager := data.NewGormTransactionManager(db)
+		userRepository := data.NewGormRepository[User, uint](transactionManager)
+
+		ctx := context.Background()
+		var created User
+		err := transactionManager.Do(ctx, func(ctx context.Context) error {
+			created, _ = userRepository.Create(ctx, User{Name: "reuben.b"})
+			return data.BeforeCommit(ctx, func(ctx context.Context) error {
+				return errors.New("before commit error")
+			})
+		})
+		assert.NotNil(t, err)
+
+		_, err = userRepository.FindOne(ctx, created.ID)
+		assert.Equal(t, data.NotFoundError, err)
+	})
+
+	t.Run("no transaction", func(t *testing.T) {
+		err := data.AfterCommit(context.Background(), func(ctx context.Context) {})
+		assert.Equal(t, data.NoTransactionError, err)
+	})
+}
@@ -0,0 +1,259 @@
+package data
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+	"reflect"
+	"sort"
+	"sync"
+)
+
+type entityKey struct {
+	entityType reflect.Type
+	id         any
+}
+
+type unitOfWorkState int
+
+const (
+	pendingInsert unitOfWorkState = iota + 1
+	pendingUpdate
+	pendingDelete
+)
+
+type unitOfWorkEntry struct {
+	key   entityKey
+	state unitOfWorkState
+	depth int // depth in belong-to dependencies. parents have less depth than children.
+	flush func(ctx context.Context) error
+}
+
+// UnitOfWork tracks entities loaded by repositories in a transaction, and defers writes to the flush at commit.
+// Creates of entities without ID are not deferred, as IDs generated by database are returned by Create.
+// Update writes only the columns changed from the snapshot taken when the entity was loaded.
+// Inserts are flushed parents first, and deletes children first, in belong-to dependency order.
+// Has-one, has-many and many-to-many associations are tracked by columns of their entities, and Update of an entity
+// whose associations changed writes all columns and merges associations by cascade, as without UnitOfWork.
+type UnitOfWork struct {
+	m         sync.Mutex
+	snapshots map[entityKey]entitySnapshot
+	entries   []*unitOfWorkEntry
+}
+
+// entitySnapshot is columns of an entity, and columns of entities of its associations to be merged by Update.
+type entitySnapshot struct {
+	columns      map[string]any
+	associations map[string][]map[string]any
+}
+
+// DeletedEntityError is returned by Update of an entity deleted in UnitOfWork.
+var DeletedEntityError = errors.New("entity is deleted in the unit of work")
+
+type unitOfWorkKey struct{}
+
+// WithUnitOfWork returns a context with a new UnitOfWork, which is flushed before the transaction of ctx commits.
+func WithUnitOfWork(ctx context.Context) (context.Context, error) {
+	uow := &UnitOfWork{snapshots: make(map[entityKey]entitySnapshot)}
+	if err := BeforeCommit(ctx, uow.Flush); err != nil {
+		return ctx, err
+	}
+	return context.WithValue(ctx, unitOfWorkKey{}, uow), nil
+}
+
+func unitOfWorkFrom(ctx context.Context) (*UnitOfWork, bool) {
+	uow, ok := ctx.Value(unitOfWorkKey{}).(*UnitOfWork)
+	return uow, ok
+}
+
+// FlushUnitOfWork flushes pending writes of UnitOfWork of ctx, e.g. to find entities created of assigned IDs before commit.
+func FlushUnitOfWork(ctx context.Context) error {
+	uow, ok := unitOfWorkFrom(ctx)
+	if !ok {
+		return fmt.Errorf("no unit of work")
+	}
+	return uow.Flush(ctx)
+}
+
+func (w *UnitOfWork) snapshot(key entityKey, snapshot entitySnapshot) {
+	w.m.Lock()
+	defer w.m.Unlock()
+	if _, ok := w.snapshots[key]; !ok {
+		w.snapshots[key] = snapshot
+	}
+}
+
+func (w *UnitOfWork) getSnapshot(key entityKey) (entitySnapshot, bool) {
+	w.m.Lock()
+	defer w.m.Unlock()
+	snapshot, ok := w.snapshots[key]
+	return snapshot, ok
+}
+
+func (w *UnitOfWork) setSnapshot(key entityKey, snapshot entitySnapshot) {
+	w.m.Lock()
+	defer w.m.Unlock()
+	w.snapshots[key] = snapshot
+}
+
+// register registers entry. The last update or delete of an entity wins, but an entity deleted is not updated.
+func (w *UnitOfWork) register(entry *unitOfWorkEntry) error {
+	w.m.Lock()
+	defer w.m.Unlock()
+	if entry.state == pendingInsert {
+		w.entries = append(w.entries, entry)
+		return nil
+	}
+	for _, v := range w.entries {
+		if entry.state == pendingUpdate && v.state == pendingDelete && v.key == entry.key {
+			return fmt.Errorf("%s[%v]: %w", entry.key.entityType.Name(), entry.key.id, DeletedEntityError)
+		}
+	}
+	entries := w.entries[:0]
+	for _, v := range w.entries {
+		if v.state != pendingInsert && v.key == entry.key {
+			continue
+		}
+		entries = append(entries, v)
+	}
+	w.entries = append(entries, entry)
+	return nil
+}
+
+// Flush writes pending inserts, updates and deletes.
+func (w *UnitOfWork) Flush(ctx context.Context) error {
+	if err := w.flushInserts(ctx); err != nil {
+		return err
+	}
+	w.m.Lock()
+	entries := w.entries
+	w.entries = nil
+	w.m.Unlock()
+
+	var updates, deletes []*unitOfWorkEntry
+	for _, entry := range entries {
+		switch entry.state {
+		case pendingUpdate:
+			updates = append(updates, entry)
+		case pendingDelete:
+			deletes = append(deletes, entry)
+		}
+	}
+	sort.SliceStable(deletes, func(i, j int) bool { return deletes[i].depth > deletes[j].depth })
+
+	logrus.Debugf("UnitOfWork.Flush: %d updates, %d deletes", len(updates), len(deletes))
+	for _, group := range [][]*unitOfWorkEntry{updates, deletes} {
+		for _, entry := range group {
+			if err := entry.flush(ctx); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// flushInserts writes pending inserts only, for an entity inserted before flush to refer to them.
+func (w *UnitOfWork) flushInserts(ctx context.Context) error {
+	w.m.Lock()
+	var inserts []*unitOfWorkEntry
+	entries := w.entries[:0]
+	for _, entry := range w.entries {
+		if entry.state == pendingInsert {
+			inserts = append(inserts, entry)
+		} else {
+			entries = append(entries, entry)
+		}
+	}
+	w.entries = entries
+	w.m.Unlock()
+
+	sort.SliceStable(inserts, func(i, j int) bool { return inserts[i].depth < inserts[j].depth })
+
+	logrus.Debugf("UnitOfWork.flushInserts: %d inserts", len(inserts))
+	for _, entry := range inserts {
+		if err := entry.flush(ctx); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// columnValues returns values of columns of entity, excluding associations.
+func columnValues(db *gorm.DB, ptrToEntity any) map[string]any {
+	stmt := &gorm.Statement{DB: db}
+	if err := stmt.Parse(ptrToEntity); err != nil {
+		panic(fmt.Sprintf("columnValues: fail to parse %T - %v", ptrToEntity, err))
+	}
+	value := reflect.Indirect(reflect.ValueOf(ptrToEntity))
+	columns := make(map[string]any, len(stmt.Schema.DBNames))
+	for _, name := range stmt.Schema.DBNames {
+		field := stmt.Schema.FieldsByDBName[name]
+		v, _ := field.ValueOf(context.Background(), value)
+		if valuer, ok := v.(driver.Valuer); ok && field.Serializer != nil {
+			// serialized value, such as JSON of a value object, to be compared with the snapshot
+			v, _ = valuer.Value()
+		}
+		columns[name] = v
+	}
+	return columns
+}
+
+// takeSnapshot returns the snapshot of entity, with associations which Update merges, that is, loaded or eager ones
+// of cascade merge. Lazy associations not loaded are not in the snapshot.
+func takeSnapshot(db *gorm.DB, ptrToEntity any) entitySnapshot {
+	snapshot := entitySnapshot{columns: columnValues(db, ptrToEntity), associations: make(map[string][]map[string]any)}
+	entityValue := reflect.ValueOf(ptrToEntity).Elem()
+	lazyLoader, _ := ptrToEntity.(LazyLoadable)
+	for _, association := range associationsOf(ptrToEntity) {
+		if (association.Type != HasOne && association.Type != HasMany && association.Type != ManyToMany) || !association.cascade.has(CascadeMerge) {
+			continue
+		}
+		field := entityValue.FieldByName(association.Name)
+		if lazyLoader != nil && lazyLoader.HasLoadFunc(association.Name) && field.IsZero() {
+			continue
+		}
+		elements := []map[string]any{}
+		for _, element := range graphElementsOf(field) {
+			if ptrToElement, ok := graphNodeOf(element); ok {
+				elements = append(elements, columnValues(db, ptrToElement))
+			}
+		}
+		snapshot.associations[association.Name] = elements
+	}
+	return snapshot
+}
+
+// changedColumns returns columns whose value differs from snapshot.
+func changedColumns(snapshot map[string]any, columns map[string]any) map[string]any {
+	changed := make(map[string]any)
+	for name, v := range columns {
+		if !reflect.DeepEqual(snapshot[name], v) {
+			changed[name] = v
+		}
+	}
+	return changed
+}
+
+// belongToDepth returns the depth of entityType in belong-to associations. Self references are ignored.
+func belongToDepth(entityType reflect.Type, visiting map[reflect.Type]bool) int {
+	if visiting[entityType] {
+		return 0
+	}
+	visiting[entityType] = true
+	defer delete(visiting, entityType)
+
+	depth := 0
+	for _, association := range metadataOf(entityType).associations {
+		if association.Type != BelongTo || association.Polymorphic != "" {
+			continue
+		}
+		parentType := association.entityType
+		if d := belongToDepth(parentType, visiting) + 1; d > depth && parentType != entityType {
+			depth = d
+		}
+	}
+	return depth
+}
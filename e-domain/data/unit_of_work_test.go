@@ -0,0 +1,246 @@
+package data_test
+
+import (
+	"context"
+	"github.com/reuben-baek/go-learning/e-domain/data"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+	"testing"
+)
+
+func TestUnitOfWork(t *testing.T) {
+	type Company struct {
+		ID   uint
+		Name string
+	}
+	type Product struct {
+		data.LazyLoader `gorm:"-"`
+		ID              uint
+		Name            string
+		Price           int
+		CompanyID       uint
+		Company         Company `gorm:"constraint:OnUpdate:CASCADE,OnDelete:SET NULL;"`
+	}
+
+	db := getGormDB()
+	db.AutoMigrate(&Company{}, &Product{})
+
+	var statements []string
+	capture := func(db *gorm.DB) {
+		statements = append(statements, db.Statement.SQL.String())
+	}
+	db.Callback().Create().After("gorm:create").Register("test:capture_create", capture)
+	db.Callback().Update().After("gorm:update").Register("test:capture_update", capture)
+	db.Callback().Delete().After("gorm:delete").Register("test:capture_delete", capture)
+
+	transactionManager := data.NewGormTransactionManager(db)
+	companyRepository := data.NewGormRepository[Company, uint](transactionManager)
+	productRepository := data.NewGormRepository[Product, uint](transactionManager)
+
+	ctx := context.Background()
+	kakao, _ := companyRepository.Create(ctx, Company{Name: "kakao"})
+	macbook, _ := productRepository.Create(ctx, Product{Name: "macbook", Price: 100, CompanyID: kakao.ID})
+
+	t.Run("update changed columns at commit", func(t *testing.T) {
+		statements = nil
+		err := transactionManager.Do(ctx, func(ctx context.Context) error {
+			ctx, err := data.WithUnitOfWork(ctx)
+			if err != nil {
+				return err
+			}
+			found, err := productRepository.FindOne(ctx, macbook.ID)
+			if err != nil {
+				return err
+			}
+			found.Price = 200
+			updated, err := productRepository.Update(ctx, found)
+			assert.Equal(t, 200, updated.Price)
+			found.Price = 300
+			productRepository.Update(ctx, found)
+
+			unchanged, _ := companyRepository.FindOne(ctx, kakao.ID)
+			companyRepository.Update(ctx, unchanged)
+
+			assert.Empty(t, statements)
+			return err
+		})
+		assert.Nil(t, err)
+		assert.Equal(t, []string{"UPDATE `products` SET `price`=? WHERE `id` = ?"}, statements)
+
+		found, _ := productRepository.FindOne(ctx, macbook.ID)
+		assert.Equal(t, 300, found.Price)
+		assert.Equal(t, macbook.Name, found.Name)
+	})
+	t.Run("insert parents first and delete children first", func(t *testing.T) {
+		statements = nil
+		err := transactionManager.Do(ctx, func(ctx context.Context) error {
+			ctx, _ = data.WithUnitOfWork(ctx)
+			productRepository.Create(ctx, Product{ID: 100, Name: "ipad", CompanyID: 100})
+			companyRepository.Create(ctx, Company{ID: 100, Name: "apple"})
+			return nil
+		})
+		assert.Nil(t, err)
+		assert.Equal(t, 2, len(statements))
+		assert.Contains(t, statements[0], "INSERT INTO `companies`")
+		assert.Contains(t, statements[1], "INSERT INTO `products`")
+
+		statements = nil
+		err = transactionManager.Do(ctx, func(ctx context.Context) error {
+			ctx, _ = data.WithUnitOfWork(ctx)
+			apple, _ := companyRepository.FindOne(ctx, 100)
+			ipad, _ := productRepository.FindOne(ctx, 100)
+			companyRepository.Delete(ctx, apple)
+			productRepository.Delete(ctx, ipad)
+			return nil
+		})
+		assert.Nil(t, err)
+		assert.Equal(t, 2, len(statements))
+		assert.Contains(t, statements[0], "DELETE FROM `products`")
+		assert.Contains(t, statements[1], "DELETE FROM `companies`")
+	})
+	t.Run("create returns generated IDs", func(t *testing.T) {
+		statements = nil
+		var created Product
+		err := transactionManager.Do(ctx, func(ctx context.Context) error {
+			ctx, _ = data.WithUnitOfWork(ctx)
+			samsung, err := companyRepository.Create(ctx, Company{Name: "samsung"})
+			if err != nil {
+				return err
+			}
+			assert.NotEmpty(t, samsung.ID)
+			companyRepository.Create(ctx, Company{ID: 300, Name: "lg"})
+			// the pending insert of lg is flushed before galaxy referring to it
+			created, err = productRepository.Create(ctx, Product{Name: "galaxy", Price: 100, CompanyID: 300})
+			if err != nil {
+				return err
+			}
+			assert.NotEmpty(t, created.ID)
+			assert.Equal(t, 3, len(statements))
+			assert.Contains(t, statements[1], "INSERT INTO `companies`")
+			assert.Contains(t, statements[2], "INSERT INTO `products`")
+
+			found, err := productRepository.FindOne(ctx, created.ID)
+			if err != nil {
+				return err
+			}
+			found.Price = 200
+			_, err = productRepository.Update(ctx, found)
+			return err
+		})
+		assert.Nil(t, err)
+		assert.Equal(t, 4, len(statements))
+		assert.Equal(t, "UPDATE `products` SET `price`=? WHERE `id` = ?", statements[3])
+
+		found, err := productRepository.FindOne(ctx, created.ID)
+		assert.Nil(t, err)
+		assert.Equal(t, "galaxy", found.Name)
+		assert.Equal(t, 200, found.Price)
+		assert.Equal(t, uint(300), found.CompanyID)
+	})
+	t.Run("flush", func(t *testing.T) {
+		err := transactionManager.Do(ctx, func(ctx context.Context) error {
+			ctx, _ = data.WithUnitOfWork(ctx)
+			productRepository.Create(ctx, Product{ID: 200, Name: "iphone", CompanyID: kakao.ID})
+			if err := data.FlushUnitOfWork(ctx); err != nil {
+				return err
+			}
+			_, err := productRepository.FindOne(ctx, 200)
+			return err
+		})
+		assert.Nil(t, err)
+	})
+	t.Run("update after delete", func(t *testing.T) {
+		err := transactionManager.Do(ctx, func(ctx context.Context) error {
+			ctx, _ = data.WithUnitOfWork(ctx)
+			found, _ := productRepository.FindOne(ctx, macbook.ID)
+			if err := productRepository.Delete(ctx, found); err != nil {
+				return err
+			}
+			found.Price = 500
+			_, err := productRepository.Update(ctx, found)
+			assert.ErrorIs(t, err, data.DeletedEntityError)
+			return nil
+		})
+		assert.Nil(t, err)
+
+		_, err = productRepository.FindOne(ctx, macbook.ID)
+		assert.Equal(t, data.NotFoundError, err)
+		macbook, _ = productRepository.Create(ctx, Product{Name: "macbook", Price: 300, CompanyID: kakao.ID})
+	})
+	t.Run("rollback", func(t *testing.T) {
+		statements = nil
+		err := transactionManager.Do(ctx, func(ctx context.Context) error {
+			ctx, _ = data.WithUnitOfWork(ctx)
+			found, _ := productRepository.FindOne(ctx, macbook.ID)
+			found.Name = "macbook pro"
+			productRepository.Update(ctx, found)
+			return data.NotFoundError
+		})
+		assert.Equal(t, data.NotFoundError, err)
+		assert.Empty(t, statements)
+	})
+	t.Run("no transaction", func(t *testing.T) {
+		_, err := data.WithUnitOfWork(ctx)
+		assert.Equal(t, data.NoTransactionError, err)
+	})
+}
+
+func TestUnitOfWork_Associations(t *testing.T) {
+	type Member struct {
+		ID     uint
+		Name   string
+		TeamID uint
+	}
+	type Team struct {
+		data.LazyLoader `gorm:"-"`
+		ID              uint
+		Name            string
+		Members         []Member
+	}
+
+	db := getGormDB()
+	db.AutoMigrate(&Team{}, &Member{})
+
+	transactionManager := data.NewGormTransactionManager(db)
+	teamRepository := data.NewGormRepository[Team, uint](transactionManager)
+
+	ctx := context.Background()
+	replaceMembers := func(ctx context.Context, id uint) error {
+		found, err := teamRepository.FindOne(ctx, id)
+		if err != nil {
+			return err
+		}
+		members, err := data.LazyLoadNow[[]Member]("Members", &found)
+		if err != nil {
+			return err
+		}
+		found.Members = []Member{members[0], {Name: "tony"}}
+		_, err = teamRepository.Update(ctx, found)
+		return err
+	}
+	memberNames := func(id uint) []string {
+		found, _ := teamRepository.FindOne(ctx, id)
+		members, _ := data.LazyLoadNow[[]Member]("Members", &found)
+		var names []string
+		for _, member := range members {
+			names = append(names, member.Name)
+		}
+		return names
+	}
+
+	storage, _ := teamRepository.Create(ctx, Team{Name: "storage", Members: []Member{{Name: "reuben"}, {Name: "ryan"}}})
+	network, _ := teamRepository.Create(ctx, Team{Name: "network", Members: []Member{{Name: "reuben"}, {Name: "ryan"}}})
+	err := transactionManager.Do(ctx, func(ctx context.Context) error {
+		ctx, _ = data.WithUnitOfWork(ctx)
+		return replaceMembers(ctx, storage.ID)
+	})
+	assert.Nil(t, err)
+	err = transactionManager.Do(ctx, func(ctx context.Context) error {
+		return replaceMembers(ctx, network.ID)
+	})
+	assert.Nil(t, err)
+
+	// associations are merged the same as Update without unit of work
+	assert.ElementsMatch(t, []string{"reuben", "tony"}, memberNames(storage.ID))
+	assert.ElementsMatch(t, memberNames(network.ID), memberNames(storage.ID))
+}
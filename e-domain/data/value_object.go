@@ -0,0 +1,72 @@
+package data
+
+import (
+	"context"
+	"fmt"
+	"gorm.io/gorm"
+	"gorm.io/gorm/schema"
+	"reflect"
+)
+
+// isValueObjectField reports whether field is a value object, mapped by GORM to prefixed columns of the entity table
+// by `gorm:"embedded;embeddedPrefix:address_"`, or to a column by a serializer such as `gorm:"serializer:json"`.
+// Value objects have no identity, and are compared by their values.
+func isValueObjectField(field reflect.StructField) bool {
+	settings := schema.ParseTagSetting(field.Tag.Get("gorm"), ";")
+	if _, ok := settings["EMBEDDED"]; ok {
+		return true
+	}
+	_, ok := settings["SERIALIZER"]
+	return ok
+}
+
+// valueObjectOf returns the value object field named name of ptrToEntity, checking value is assignable to it.
+func valueObjectOf(ptrToEntity any, name string, value any) (reflect.Value, error) {
+	entityValue := reflect.ValueOf(ptrToEntity).Elem()
+	metadata := metadataOf(entityValue.Type())
+	field, ok := metadata.field(entityValue, name)
+	if !ok || !metadata.valueObjects[name] {
+		return reflect.Value{}, fmt.Errorf("%s has no value object %s", entityValue.Type().Name(), name)
+	}
+	if value == nil || !reflect.TypeOf(value).AssignableTo(field.Type()) {
+		return reflect.Value{}, fmt.Errorf("value object %s.%s is %s, not %T", entityValue.Type().Name(), name, field.Type(), value)
+	}
+	return field, nil
+}
+
+// valueObjectConditions returns conditions of every column of the value object named name equal to value,
+// which are the prefixed columns of an embedded value object, or the serialized column.
+func valueObjectConditions(db *gorm.DB, ptrToEntity any, name string, value any) (map[string]any, error) {
+	field, err := valueObjectOf(ptrToEntity, name, value)
+	if err != nil {
+		return nil, err
+	}
+	entitySchema, err := parseSchema(db, ptrToEntity)
+	if err != nil {
+		return nil, err
+	}
+	field.Set(reflect.ValueOf(value))
+
+	entityValue := reflect.ValueOf(ptrToEntity).Elem()
+	conditions := make(map[string]any)
+	for _, f := range entitySchema.Fields {
+		if f.DBName == "" || len(f.BindNames) == 0 || f.BindNames[0] != name {
+			continue
+		}
+		// serializers value the column by the field value, such as JSON of it
+		conditions[f.DBName], _ = f.ValueOf(context.Background(), entityValue)
+	}
+	if len(conditions) == 0 {
+		return nil, fmt.Errorf("%s has no column of value object %s", entitySchema.Name, name)
+	}
+	return conditions, nil
+}
+
+// equalValueObject reports whether the value object named name of entityValue equals value, by their values.
+func equalValueObject(entityValue reflect.Value, name string, value any) bool {
+	field, ok := metadataOf(entityValue.Type()).field(entityValue, name)
+	if !ok {
+		return false
+	}
+	return reflect.DeepEqual(field.Interface(), value)
+}
@@ -0,0 +1,184 @@
+package data_test
+
+import (
+	"context"
+	"github.com/reuben-baek/go-learning/e-domain/data"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+	"testing"
+)
+
+type VOMoney struct {
+	Amount   int64
+	Currency string
+}
+
+type VOAddress struct {
+	Street string
+	City   string
+}
+
+type VOOrder struct {
+	data.LazyLoader `gorm:"-"`
+	ID              uint
+	Name            string
+	ShipTo          VOAddress `gorm:"embedded;embeddedPrefix:ship_to_"`
+	Total           VOMoney   `gorm:"serializer:json"`
+}
+
+// VOOrderDto is a DTO of VOOrder, sharing its value objects.
+type VOOrderDto struct {
+	ID     uint
+	Name   string
+	ShipTo VOAddress `gorm:"embedded;embeddedPrefix:ship_to_"`
+	Total  VOMoney   `gorm:"serializer:json"`
+}
+
+func (VOOrderDto) TableName() string {
+	return "vo_orders"
+}
+
+func (d VOOrderDto) To() VOOrder {
+	return VOOrder{ID: d.ID, Name: d.Name, ShipTo: d.ShipTo, Total: d.Total}
+}
+
+func (d VOOrderDto) From(m VOOrder) any {
+	return VOOrderDto{ID: m.ID, Name: m.Name, ShipTo: m.ShipTo, Total: m.Total}
+}
+
+func TestGormRepository_ValueObject(t *testing.T) {
+	db := getGormDB()
+	db.AutoMigrate(&VOOrder{})
+	var statements []string
+	db.Callback().Update().After("gorm:update").Register("test:value_object_statements", func(tx *gorm.DB) {
+		statements = append(statements, tx.Statement.SQL.String())
+	})
+
+	transactionManager := data.NewGormTransactionManager(db)
+	orderRepository := data.NewGormRepository[VOOrder, uint](transactionManager)
+
+	pangyo := VOAddress{Street: "235 Pangyoyeok-ro", City: "Seongnam"}
+	jeju := VOAddress{Street: "242 Cheomdan-ro", City: "Jeju"}
+	won := func(amount int64) VOMoney { return VOMoney{Amount: amount, Currency: "KRW"} }
+
+	ctx := context.Background()
+	var order VOOrder
+	t.Run("round trip", func(t *testing.T) {
+		err := transactionManager.Do(ctx, func(ctx context.Context) error {
+			var err error
+			order, err = orderRepository.Create(ctx, VOOrder{Name: "order-1", ShipTo: pangyo, Total: won(1000)})
+			assert.Nil(t, err)
+			orderRepository.Create(ctx, VOOrder{Name: "order-2", ShipTo: jeju, Total: won(1000)})
+			orderRepository.Create(ctx, VOOrder{Name: "order-3", ShipTo: pangyo, Total: VOMoney{Amount: 1000, Currency: "USD"}})
+			return err
+		})
+		assert.Nil(t, err)
+
+		var columns map[string]any
+		db.Table("vo_orders").Where("id = ?", order.ID).Take(&columns)
+		assert.Equal(t, pangyo.City, columns["ship_to_city"])
+		assert.JSONEq(t, `{"Amount":1000,"Currency":"KRW"}`, columns["total"].(string))
+
+		err = transactionManager.Do(ctx, func(ctx context.Context) error {
+			found, err := orderRepository.FindOne(ctx, order.ID)
+			assert.Nil(t, err)
+			assert.Equal(t, pangyo, found.ShipTo)
+			assert.Equal(t, won(1000), found.Total)
+			return nil
+		})
+		assert.Nil(t, err)
+	})
+	t.Run("find by value", func(t *testing.T) {
+		names := func(orders []VOOrder) []string {
+			var names []string
+			for _, order := range orders {
+				names = append(names, order.Name)
+			}
+			return names
+		}
+		err := transactionManager.Do(ctx, func(ctx context.Context) error {
+			orders, err := orderRepository.FindByValue(ctx, "ShipTo", pangyo)
+			assert.Nil(t, err)
+			assert.Equal(t, []string{"order-1", "order-3"}, names(orders))
+
+			orders, err = orderRepository.FindByValue(ctx, "Total", won(1000))
+			assert.Nil(t, err)
+			assert.Equal(t, []string{"order-1", "order-2"}, names(orders))
+
+			orders, err = orderRepository.FindByValue(ctx, "ShipTo", pangyo.City)
+			assert.ErrorContains(t, err, "value object VOOrder.ShipTo is data_test.VOAddress, not string")
+			_, err = orderRepository.FindByValue(ctx, "Name", "order-1")
+			assert.ErrorContains(t, err, "VOOrder has no value object Name")
+			return nil
+		})
+		assert.Nil(t, err)
+	})
+	t.Run("unit of work updates changed columns of value objects", func(t *testing.T) {
+		statements = nil
+		err := transactionManager.Do(ctx, func(ctx context.Context) error {
+			ctx, err := data.WithUnitOfWork(ctx)
+			if err != nil {
+				return err
+			}
+			found, err := orderRepository.FindOne(ctx, order.ID)
+			if err != nil {
+				return err
+			}
+			found.ShipTo = jeju
+			_, err = orderRepository.Update(ctx, found)
+			return err
+		})
+		assert.Nil(t, err)
+		assert.Equal(t, []string{"UPDATE `vo_orders` SET `ship_to_city`=?,`ship_to_street`=? WHERE `id` = ?"}, statements)
+
+		statements = nil
+		err = transactionManager.Do(ctx, func(ctx context.Context) error {
+			ctx, _ = data.WithUnitOfWork(ctx)
+			found, err := orderRepository.FindOne(ctx, order.ID)
+			if err != nil {
+				return err
+			}
+			found.Total = won(3000)
+			_, err = orderRepository.Update(ctx, found)
+			return err
+		})
+		assert.Nil(t, err)
+		assert.Equal(t, []string{"UPDATE `vo_orders` SET `total`=? WHERE `id` = ?"}, statements)
+		found, _ := orderRepository.FindOne(ctx, order.ID)
+		assert.Equal(t, won(3000), found.Total)
+		assert.Equal(t, jeju, found.ShipTo)
+	})
+	t.Run("dto", func(t *testing.T) {
+		repository := data.NewDtoWrapFindByValueRepository[VOOrderDto, VOOrder](data.NewGormRepository[VOOrderDto, uint](transactionManager))
+		err := transactionManager.Do(ctx, func(ctx context.Context) error {
+			orders, err := repository.FindByValue(ctx, "ShipTo", jeju)
+			assert.Nil(t, err)
+			assert.Equal(t, 2, len(orders))
+			assert.Equal(t, jeju, orders[0].ShipTo)
+			return nil
+		})
+		assert.Nil(t, err)
+	})
+	t.Run("in-memory", func(t *testing.T) {
+		dummyTransactionManager := data.NewDummyTransactionManager()
+		repository := data.NewInMemoryRepository[VOOrder, uint](dummyTransactionManager)
+		dummyTransactionManager.Do(ctx, func(ctx context.Context) error {
+			repository.Create(ctx, VOOrder{ID: 1, Name: "order-1", ShipTo: pangyo, Total: won(1000)})
+			repository.Create(ctx, VOOrder{ID: 2, Name: "order-2", ShipTo: jeju, Total: won(2000)})
+
+			found, err := repository.FindOne(ctx, 1)
+			assert.Nil(t, err)
+			assert.True(t, found.ShipTo == pangyo)
+			assert.True(t, found.Total == won(1000))
+
+			orders, err := repository.FindByValue(ctx, "Total", won(2000))
+			assert.Nil(t, err)
+			assert.Equal(t, 1, len(orders))
+			assert.Equal(t, "order-2", orders[0].Name)
+
+			_, err = repository.FindByValue(ctx, "Name", "order-1")
+			assert.ErrorContains(t, err, "VOOrder has no value object Name")
+			return nil
+		})
+	})
+}
@@ -1 +1,64 @@
 package e_domain
+
+import "fmt"
+
+// Money is a value object of an amount in the minor unit of a currency, such as cents of USD.
+// Value objects have no identity and are compared by their values with ==. They are never modified in place,
+// and operations return new values.
+//
+// Entities keep value objects in columns of their table, prefixed ones by `gorm:"embedded;embeddedPrefix:price_"`,
+// or a JSON column by `gorm:"serializer:json"`.
+type Money struct {
+	Amount   int64
+	Currency string
+}
+
+func NewMoney(amount int64, currency string) Money {
+	return Money{Amount: amount, Currency: currency}
+}
+
+func (m Money) Add(other Money) (Money, error) {
+	if m.Currency != other.Currency {
+		return Money{}, fmt.Errorf("Money.Add: currency %s is not %s", other.Currency, m.Currency)
+	}
+	return NewMoney(m.Amount+other.Amount, m.Currency), nil
+}
+
+func (m Money) Multiply(n int64) Money {
+	return NewMoney(m.Amount*n, m.Currency)
+}
+
+func (m Money) Equals(other Money) bool {
+	return m == other
+}
+
+func (m Money) IsZero() bool {
+	return m.Amount == 0
+}
+
+func (m Money) String() string {
+	return fmt.Sprintf("%d %s", m.Amount, m.Currency)
+}
+
+// Address is a value object of a postal address.
+type Address struct {
+	Street  string
+	City    string
+	ZipCode string
+}
+
+func NewAddress(street string, city string, zipCode string) Address {
+	return Address{Street: street, City: city, ZipCode: zipCode}
+}
+
+func (a Address) WithStreet(street string) Address {
+	return NewAddress(street, a.City, a.ZipCode)
+}
+
+func (a Address) Equals(other Address) bool {
+	return a == other
+}
+
+func (a Address) String() string {
+	return fmt.Sprintf("%s, %s %s", a.Street, a.City, a.ZipCode)
+}